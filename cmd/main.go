@@ -1,11 +1,22 @@
 package main
 
 import (
+	"car-rental/pkg/auth"
 	"car-rental/pkg/database"
+	"car-rental/pkg/gateway"
+	"car-rental/pkg/idempotency"
+	"car-rental/pkg/ratelimit"
 	"car-rental/pkg/routes"
+	"car-rental/pkg/scheduler"
+	"car-rental/pkg/utils"
+	"context"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -17,10 +28,40 @@ func main() {
 		log.Println("No .env file found or error loading .env file")
 	}
 
+	// Access tokens are signed with a secret from the environment; there is no default
+	tokens, err := auth.NewIssuer(auth.Config{
+		Secret:          []byte(os.Getenv("JWT_SECRET")),
+		AccessTokenTTL:  utils.GetEnvDuration("ACCESS_TOKEN_TTL", auth.DEFAULT_ACCESS_TOKEN_TTL),
+		RefreshTokenTTL: utils.GetEnvDuration("REFRESH_TOKEN_TTL", auth.DEFAULT_REFRESH_TOKEN_TTL),
+	})
+	if err != nil {
+		log.Fatal("Invalid token settings: ", err)
+	}
+	auth.Tokens = tokens
+
 	database.Connect()
 	database.Migrate()
 	database.SeedData()
 
+	// Stop background jobs and the server on SIGINT/SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Start background jobs
+	scheduler.Runner = scheduler.New(database.DB, scheduler.SystemClock{}, scheduler.ConfigFromEnv())
+	go scheduler.Runner.Start(ctx)
+
+	// Register payment providers. The fake provider marks charges paid on request, so it is
+	// only registered when explicitly enabled and never with a guessable secret.
+	if strings.ToLower(os.Getenv("FAKE_GATEWAY_ENABLED")) == "true" {
+		fake, err := gateway.NewFakeProvider(os.Getenv("FAKE_GATEWAY_SECRET"))
+		if err != nil {
+			log.Fatal("Invalid fake gateway settings: ", err)
+		}
+		gateway.Register(fake)
+		log.Println("Fake payment gateway is enabled; do not use it in production")
+	}
+
 	// Set Gin mode
 	if os.Getenv("GIN_MODE") == "" {
 		gin.SetMode(gin.DebugMode)
@@ -31,12 +72,22 @@ func main() {
 	// Initialize Gin router
 	r := gin.Default()
 
-	// Configure CORS to allow all methods
-	config := cors.DefaultConfig()
-	config.AllowAllOrigins = true
-	config.AllowMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "PATCH"}
-	config.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization"}
-	r.Use(cors.New(config))
+	// Configure CORS for the browser origins listed in CORS_ALLOWED_ORIGINS; without any,
+	// cross-origin requests are refused. "*" keeps the old behaviour of allowing every origin.
+	if origins := utils.GetEnvList("CORS_ALLOWED_ORIGINS"); len(origins) > 0 {
+		config := cors.DefaultConfig()
+		if len(origins) == 1 && origins[0] == "*" {
+			config.AllowAllOrigins = true
+		} else {
+			config.AllowOrigins = origins
+		}
+		config.AllowMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "PATCH"}
+		config.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization", auth.API_KEY_HEADER, idempotency.HEADER}
+		config.ExposeHeaders = []string{"Retry-After", "X-RateLimit-Limit", "X-RateLimit-Remaining", idempotency.REPLAYED_HEADER}
+		r.Use(cors.New(config))
+	} else {
+		log.Println("CORS_ALLOWED_ORIGINS is not set, cross-origin requests are refused; set it to * to allow every origin as before")
+	}
 
 	// Configure trusted proxies
 	trustedProxies := os.Getenv("TRUSTED_PROXIES")
@@ -51,6 +102,12 @@ func main() {
 		r.SetTrustedProxies(strings.Split(trustedProxies, ","))
 	}
 
+	// Requests retried with the same Idempotency-Key get the first response back
+	idempotency.Default = idempotency.New(database.DB, utils.GetEnvDuration("IDEMPOTENCY_KEY_TTL", idempotency.DEFAULT_TTL))
+
+	// Limit requests per client; the in-memory store counts for this node only
+	ratelimit.Default = ratelimit.New(ratelimit.NewMemoryStore(), ratelimit.ConfigFromEnv())
+
 	// Setup routes
 	routes.SetupRoutes(r)
 
@@ -59,8 +116,23 @@ func main() {
 		port = "8080"
 	}
 
+	server := &http.Server{
+		Addr:    ":" + port,
+		Handler: r,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.Println("Failed to shut down server gracefully:", err)
+		}
+	}()
+
 	log.Printf("Starting server on port %s", port)
-	if err := r.Run(":" + port); err != nil {
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 		log.Fatal("Failed to start server:", err)
 	}
+	log.Println("Server stopped")
 }
@@ -0,0 +1,64 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CONTEXT_CLAIMS is the gin context key holding the claims of a verified token
+const CONTEXT_CLAIMS = "auth_claims"
+
+// RequireToken rejects requests without a valid bearer access token with 401 and stores
+// the token's claims on the context for the handlers
+func RequireToken(issuer *Issuer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		scheme, token, found := strings.Cut(c.GetHeader("Authorization"), " ")
+		if !found || !strings.EqualFold(scheme, TOKEN_TYPE) || token == "" {
+			abortUnauthorized(c, "Missing bearer token")
+			return
+		}
+
+		claims, err := issuer.Verify(strings.TrimSpace(token), time.Now())
+		if errors.Is(err, ErrExpiredToken) {
+			abortUnauthorized(c, "Access token has expired")
+			return
+		}
+		if err != nil {
+			abortUnauthorized(c, "Invalid access token")
+			return
+		}
+
+		c.Set(CONTEXT_CLAIMS, claims)
+		c.Next()
+	}
+}
+
+// CurrentClaims returns the claims stored by RequireToken, or nil on public routes
+func CurrentClaims(c *gin.Context) *Claims {
+	if claims, ok := c.Get(CONTEXT_CLAIMS); ok {
+		return claims.(*Claims)
+	}
+	return nil
+}
+
+// ClientKey names the caller of a request: its API key, else its user, else its IP. The IP
+// comes from gin's ClientIP, which only trusts forwarding headers from TRUSTED_PROXIES.
+func ClientKey(c *gin.Context) string {
+	if apiKey := CurrentAPIKey(c); apiKey != nil {
+		return "api_key:" + strconv.Itoa(apiKey.No)
+	}
+	if claims := CurrentClaims(c); claims != nil {
+		return "user:" + claims.Subject
+	}
+	return "ip:" + c.ClientIP()
+}
+
+func abortUnauthorized(c *gin.Context, message string) {
+	c.Header("WWW-Authenticate", TOKEN_TYPE)
+	c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": message})
+}
@@ -0,0 +1,30 @@
+package auth
+
+import (
+	"sync"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// dummyHash is checked instead of a stored hash for unknown users, so signing in with an
+// unknown username takes as long as with a wrong password and does not reveal which it was
+var dummyHash = sync.OnceValue(func() []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("not the password of any user"), bcrypt.DefaultCost)
+	return hash
+})
+
+// HashPassword returns the bcrypt hash stored for a password
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	return string(hash), err
+}
+
+// CheckPassword reports whether a password matches a stored hash. An empty hash, as for an
+// unknown user, never matches but costs the same bcrypt comparison.
+func CheckPassword(hash, password string) bool {
+	if hash == "" {
+		bcrypt.CompareHashAndPassword(dummyHash(), []byte(password))
+		return false
+	}
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
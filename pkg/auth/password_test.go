@@ -0,0 +1,30 @@
+package auth
+
+import "testing"
+
+func TestCheckPassword(t *testing.T) {
+	hash, err := HashPassword("correct horse")
+	if err != nil {
+		t.Fatalf("hash password: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		hash     string
+		password string
+		want     bool
+	}{
+		{name: "matching password", hash: hash, password: "correct horse", want: true},
+		{name: "wrong password", hash: hash, password: "battery staple", want: false},
+		{name: "unknown user", hash: "", password: "correct horse", want: false},
+		{name: "unknown user with the dummy password", hash: "", password: "not the password of any user", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CheckPassword(tt.hash, tt.password); got != tt.want {
+				t.Errorf("CheckPassword() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
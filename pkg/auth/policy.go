@@ -0,0 +1,98 @@
+package auth
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CONTEXT_OWN_RECORDS_ONLY is set on the gin context when the user may only see the
+// records linked to their account
+const CONTEXT_OWN_RECORDS_ONLY = "auth_own_records_only"
+
+// Forbidden constraint constants, reported in the body of 403 responses
+const (
+	FORBIDDEN_ROLE    = "role_not_allowed"
+	FORBIDDEN_NOT_OWN = "not_own_record"
+	FORBIDDEN_SCOPE   = "scope_missing"
+)
+
+// Policy says which roles may use a route. Roles listed in OwnRecords may use it too, but
+// the handler only shows them the records linked to their account.
+type Policy struct {
+	Roles      []string
+	OwnRecords []string
+}
+
+// ForbiddenError is the body of every 403 response
+type ForbiddenError struct {
+	Message    string                 `json:"error"`
+	Constraint string                 `json:"constraint"`
+	Details    map[string]interface{} `json:"details"`
+}
+
+// Authorize checks the role in the access token against a policy. It must run after
+// RequireToken.
+func Authorize(policy Policy) gin.HandlerFunc {
+	allowed := append(append([]string{}, policy.Roles...), policy.OwnRecords...)
+
+	return func(c *gin.Context) {
+		claims := CurrentClaims(c)
+		if claims == nil {
+			abortUnauthorized(c, "Missing bearer token")
+			return
+		}
+
+		switch {
+		case containsRole(policy.Roles, claims.Role):
+			c.Next()
+		case containsRole(policy.OwnRecords, claims.Role):
+			c.Set(CONTEXT_OWN_RECORDS_ONLY, true)
+			c.Next()
+		default:
+			c.AbortWithStatusJSON(http.StatusForbidden, ForbiddenError{
+				Message:    "Your role does not allow this action.",
+				Constraint: FORBIDDEN_ROLE,
+				Details: map[string]interface{}{
+					"role":          claims.Role,
+					"allowed_roles": allowed,
+				},
+			})
+		}
+	}
+}
+
+// OwnRecordsOnly reports whether the handler must limit the response to the user's own records
+func OwnRecordsOnly(c *gin.Context) bool {
+	return c.GetBool(CONTEXT_OWN_RECORDS_ONLY)
+}
+
+// RespondNotOwnRecord answers 403 when a user or partner limited to their own records asks
+// for another one
+func RespondNotOwnRecord(c *gin.Context, entityType string, entityID int) {
+	details := map[string]interface{}{
+		"entity_type": entityType,
+		"entity_id":   entityID,
+	}
+	if claims := CurrentClaims(c); claims != nil {
+		details["role"] = claims.Role
+	}
+	if apiKey := CurrentAPIKey(c); apiKey != nil {
+		details["partner_id"] = apiKey.PartnerID
+	}
+
+	c.AbortWithStatusJSON(http.StatusForbidden, ForbiddenError{
+		Message:    "You can only access your own " + entityType + " records.",
+		Constraint: FORBIDDEN_NOT_OWN,
+		Details:    details,
+	})
+}
+
+func containsRole(roles []string, role string) bool {
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,142 @@
+package auth
+
+import (
+	"car-rental/pkg/models"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token was already used")
+)
+
+// TokenPair is what login and refresh return
+type TokenPair struct {
+	AccessToken      string       `json:"access_token"`
+	TokenType        string       `json:"token_type"`
+	ExpiresIn        int          `json:"expires_in"` // Seconds until the access token expires
+	RefreshToken     string       `json:"refresh_token"`
+	RefreshExpiresAt time.Time    `json:"refresh_expires_at"`
+	User             *models.User `json:"user"`
+}
+
+// IssueTokens signs an access token and stores a new refresh token for a user
+func (i *Issuer) IssueTokens(db *gorm.DB, user *models.User, now time.Time) (*TokenPair, *models.RefreshToken, error) {
+	accessToken, err := i.AccessToken(user, now)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	refreshToken, err := newRefreshToken()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	stored := models.RefreshToken{
+		UserID:    user.No,
+		TokenHash: HashRefreshToken(refreshToken),
+		ExpiresAt: now.Add(i.config.RefreshTokenTTL),
+	}
+	if err := db.Create(&stored).Error; err != nil {
+		return nil, nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:      accessToken,
+		TokenType:        TOKEN_TYPE,
+		ExpiresIn:        int(i.config.AccessTokenTTL.Seconds()),
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: stored.ExpiresAt,
+		User:             user,
+	}, &stored, nil
+}
+
+// Refresh trades a refresh token for a new pair and revokes it. A token that was already
+// traded means it leaked, so every refresh token of its user is revoked.
+func (i *Issuer) Refresh(db *gorm.DB, refreshToken string, now time.Time) (*TokenPair, error) {
+	var pair *TokenPair
+	reused := false
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// Locking the row makes two refreshes with the same token run one after the other
+		var stored models.RefreshToken
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_hash = ?", HashRefreshToken(refreshToken)).
+			First(&stored).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidRefreshToken
+		}
+		if err != nil {
+			return err
+		}
+
+		if stored.RevokedAt != nil {
+			reused = stored.ReplacedByID != nil
+			if reused {
+				return RevokeUserTokens(tx, stored.UserID, now)
+			}
+			return ErrInvalidRefreshToken
+		}
+		if !now.Before(stored.ExpiresAt) {
+			return ErrInvalidRefreshToken
+		}
+
+		var user models.User
+		if err := tx.First(&user, stored.UserID).Error; err != nil {
+			return err
+		}
+		if !user.Active {
+			return ErrInvalidRefreshToken
+		}
+
+		var replacement *models.RefreshToken
+		pair, replacement, err = i.IssueTokens(tx, &user, now)
+		if err != nil {
+			return err
+		}
+		return tx.Model(&stored).Updates(map[string]interface{}{"revoked_at": now, "replaced_by_id": replacement.No}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	if reused {
+		return nil, ErrRefreshTokenReused
+	}
+	return pair, nil
+}
+
+// RevokeRefreshToken signs one refresh token out. Unknown and revoked tokens are ignored.
+func RevokeRefreshToken(db *gorm.DB, refreshToken string, now time.Time) error {
+	return db.Model(&models.RefreshToken{}).
+		Where("token_hash = ? AND revoked_at IS NULL", HashRefreshToken(refreshToken)).
+		Update("revoked_at", now).Error
+}
+
+// RevokeUserTokens signs a user out of every session
+func RevokeUserTokens(db *gorm.DB, userID int, now time.Time) error {
+	return db.Model(&models.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", now).Error
+}
+
+// HashRefreshToken is the SHA-256 hash a refresh token is stored and looked up by
+func HashRefreshToken(refreshToken string) string {
+	sum := sha256.Sum256([]byte(refreshToken))
+	return hex.EncodeToString(sum[:])
+}
+
+func newRefreshToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,125 @@
+// Package auth signs and checks the access tokens of staff users, rotates their refresh
+// tokens and guards routes with a gin middleware
+package auth
+
+import (
+	"car-rental/pkg/models"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Default token lifetimes
+const (
+	DEFAULT_ACCESS_TOKEN_TTL  = 15 * time.Minute
+	DEFAULT_REFRESH_TOKEN_TTL = 7 * 24 * time.Hour
+	MIN_SECRET_LENGTH         = 32
+)
+
+// Fixed parts of every access token
+const (
+	TOKEN_ISSUER    = "car-rental"
+	TOKEN_ALGORITHM = "HS256"
+	TOKEN_TYPE      = "Bearer"
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid token")
+	ErrExpiredToken = errors.New("token has expired")
+)
+
+// Config holds the signing secret and how long tokens stay valid
+type Config struct {
+	Secret          []byte
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+}
+
+// Claims is the payload of an access token: the registered iss, sub (user number), iat and
+// exp claims plus the user's role and the record it is limited to
+type Claims struct {
+	Username   string `json:"username"`
+	Role       string `json:"role"`
+	DriverID   *int   `json:"driver_id,omitempty"`   // Driver the user may see, for the driver role
+	CustomerID *int   `json:"customer_id,omitempty"` // Customer the user may see, for the customer role
+	jwt.RegisteredClaims
+}
+
+// UserID is the number of the user the token was issued to
+func (c Claims) UserID() (int, error) {
+	return strconv.Atoi(c.Subject)
+}
+
+// Issuer signs access tokens and checks the ones sent back
+type Issuer struct {
+	config Config
+}
+
+// Tokens is the issuer set up by the application, used by the auth handlers and middleware
+var Tokens *Issuer
+
+// NewIssuer checks the configuration. There is no default secret: a missing or short one
+// is an error.
+func NewIssuer(config Config) (*Issuer, error) {
+	if len(config.Secret) < MIN_SECRET_LENGTH {
+		return nil, fmt.Errorf("the signing secret must be at least %d characters", MIN_SECRET_LENGTH)
+	}
+	if config.AccessTokenTTL <= 0 || config.RefreshTokenTTL <= 0 {
+		return nil, errors.New("token lifetimes must be positive")
+	}
+	return &Issuer{config: config}, nil
+}
+
+// AccessTokenTTL is how long an access token stays valid
+func (i *Issuer) AccessTokenTTL() time.Duration {
+	return i.config.AccessTokenTTL
+}
+
+// RefreshTokenTTL is how long a refresh token stays valid
+func (i *Issuer) RefreshTokenTTL() time.Duration {
+	return i.config.RefreshTokenTTL
+}
+
+// AccessToken signs a JWT (HS256) for a user
+func (i *Issuer) AccessToken(user *models.User, now time.Time) (string, error) {
+	claims := Claims{
+		Username:   user.Username,
+		Role:       user.Role,
+		DriverID:   user.DriverID,
+		CustomerID: user.CustomerID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    TOKEN_ISSUER,
+			Subject:   strconv.Itoa(user.No),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(i.config.AccessTokenTTL)),
+		},
+	}
+
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(i.config.Secret)
+}
+
+// Verify checks the signature, issuer and expiry of an access token. The algorithm is fixed,
+// so a token claiming any other (or "none") is refused.
+func (i *Issuer) Verify(token string, now time.Time) (*Claims, error) {
+	parser := jwt.NewParser(
+		jwt.WithValidMethods([]string{TOKEN_ALGORITHM}),
+		jwt.WithIssuer(TOKEN_ISSUER),
+		jwt.WithExpirationRequired(),
+		jwt.WithTimeFunc(func() time.Time { return now }),
+	)
+
+	var claims Claims
+	_, err := parser.ParseWithClaims(token, &claims, func(*jwt.Token) (interface{}, error) {
+		return i.config.Secret, nil
+	})
+	if errors.Is(err, jwt.ErrTokenExpired) {
+		return nil, ErrExpiredToken
+	}
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	return &claims, nil
+}
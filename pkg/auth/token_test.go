@@ -0,0 +1,111 @@
+package auth
+
+import (
+	"car-rental/pkg/models"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const TEST_SECRET = "0123456789abcdef0123456789abcdef"
+
+// handSigned builds a token from the given header and claims signed with secret, so tests
+// can send tokens the issuer would never make
+func handSigned(t *testing.T, secret string, header map[string]string, claims Claims) string {
+	t.Helper()
+
+	encode := func(v interface{}) string {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatalf("encode token: %v", err)
+		}
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+
+	unsigned := encode(header) + "." + encode(claims)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(unsigned))
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerify(t *testing.T) {
+	issuer, err := NewIssuer(Config{Secret: []byte(TEST_SECRET), AccessTokenTTL: 15 * time.Minute, RefreshTokenTTL: time.Hour})
+	if err != nil {
+		t.Fatalf("new issuer: %v", err)
+	}
+
+	now := time.Date(2030, 3, 10, 9, 0, 0, 0, time.UTC)
+	customerID := 7
+	user := &models.User{No: 42, Username: "siti", Role: models.ROLE_CUSTOMER, CustomerID: &customerID}
+
+	token, err := issuer.AccessToken(user, now)
+	if err != nil {
+		t.Fatalf("sign token: %v", err)
+	}
+	parts := strings.Split(token, ".")
+
+	claims := Claims{Username: "siti", Role: models.ROLE_ADMIN, RegisteredClaims: jwt.RegisteredClaims{
+		Issuer: TOKEN_ISSUER, Subject: "42", IssuedAt: jwt.NewNumericDate(now), ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+	}}
+	hs256 := map[string]string{"alg": TOKEN_ALGORITHM, "typ": "JWT"}
+	otherIssuer := claims
+	otherIssuer.Issuer = "someone-else"
+	withoutExpiry := claims
+	withoutExpiry.ExpiresAt = nil
+
+	tests := []struct {
+		name    string
+		token   string
+		at      time.Time
+		wantErr error
+	}{
+		{"valid token", token, now, nil},
+		{"just before expiry", token, now.Add(15*time.Minute - time.Second), nil},
+		{"expired", token, now.Add(15*time.Minute + time.Second), ErrExpiredToken},
+		{"not a token", "abc", now, ErrInvalidToken},
+		{"empty", "", now, ErrInvalidToken},
+		{"missing signature", parts[0] + "." + parts[1], now, ErrInvalidToken},
+		{"claims swapped", parts[0] + "." + strings.Split(handSigned(t, TEST_SECRET, hs256, claims), ".")[1] + "." + parts[2], now, ErrInvalidToken},
+		{"signed with another secret", handSigned(t, "another-secret-of-at-least-32-chars", hs256, claims), now, ErrInvalidToken},
+		{"unsigned", strings.Join(strings.Split(handSigned(t, TEST_SECRET, map[string]string{"alg": "none", "typ": "JWT"}, claims), ".")[:2], ".") + ".", now, ErrInvalidToken},
+		{"another algorithm", handSigned(t, TEST_SECRET, map[string]string{"alg": "HS512", "typ": "JWT"}, claims), now, ErrInvalidToken},
+		{"another issuer", handSigned(t, TEST_SECRET, hs256, otherIssuer), now, ErrInvalidToken},
+		{"without expiry", handSigned(t, TEST_SECRET, hs256, withoutExpiry), now, ErrInvalidToken},
+		{"hand-signed with the secret", handSigned(t, TEST_SECRET, hs256, claims), now, nil},
+	}
+
+	for _, tt := range tests {
+		got, err := issuer.Verify(tt.token, tt.at)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: error %v, want %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if tt.wantErr == nil && (got == nil || got.Subject == "" || got.Username == "") {
+			t.Errorf("%s: claims %+v, want the signed user", tt.name, got)
+		}
+	}
+
+	verified, err := issuer.Verify(token, now)
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if id, _ := verified.UserID(); id != user.No || verified.Role != user.Role || verified.CustomerID == nil || *verified.CustomerID != customerID {
+		t.Errorf("claims = %+v, want user %d with role %s and customer %d", verified, user.No, user.Role, customerID)
+	}
+	if verified.ExpiresAt == nil || !verified.ExpiresAt.Equal(now.Add(15*time.Minute)) {
+		t.Errorf("expires at %v, want %v", verified.ExpiresAt, now.Add(15*time.Minute))
+	}
+}
+
+func TestNewIssuerRefusesShortSecrets(t *testing.T) {
+	if _, err := NewIssuer(Config{Secret: []byte("short"), AccessTokenTTL: time.Minute, RefreshTokenTTL: time.Hour}); err == nil {
+		t.Errorf("a short secret was accepted")
+	}
+}
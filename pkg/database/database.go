@@ -45,18 +45,31 @@ func Connect() {
 		dbHost, dbUser, dbPassword, dbName, dbPort, dbSSLMode)
 
 	// Connect to database
-	DB, err = gorm.Open(postgres.New(postgres.Config{
+	DB, err = Open(dsn)
+	if err != nil {
+		log.Fatal("Failed to connect to database:", err)
+	}
+
+	fmt.Println("Database connected successfully")
+}
+
+// Open connects to the PostgreSQL database at dsn with the settings the server uses
+func Open(dsn string) (*gorm.DB, error) {
+	return gorm.Open(postgres.New(postgres.Config{
 		DSN:                  dsn,
 		PreferSimpleProtocol: true,
 	}), &gorm.Config{
 		PrepareStmt: false,
 	})
+}
 
-	if err != nil {
-		log.Fatal("Failed to connect to database:", err)
-	}
-
-	fmt.Println("Database connected successfully")
+// migrationModels lists every model whose table is managed by AutoMigrate
+func migrationModels() []interface{} {
+	return []interface{}{&models.Customer{}, &models.Car{}, &models.Booking{}, &models.Membership{},
+		&models.BookingType{}, &models.DriverIncentive{}, &models.Driver{}, &models.CarUnit{},
+		&models.CancellationPolicyTier{}, &models.RateRule{}, &models.Voucher{}, &models.VoucherRedemption{},
+		&models.Payment{}, &models.GatewayCharge{}, &models.GatewayRefund{}, &models.Invoice{}, &models.InvoiceLine{}, &models.InvoiceSequence{}, &models.TaxRate{}, &models.ExchangeRate{},
+		&models.User{}, &models.RefreshToken{}, &models.Partner{}, &models.APIKey{}, &models.IdempotencyKey{}}
 }
 
 func Migrate() {
@@ -67,21 +80,31 @@ func Migrate() {
 		return
 	}
 
-	err := DB.AutoMigrate(&models.Customer{}, &models.Car{}, &models.Booking{}, &models.Membership{},
-		&models.BookingType{}, &models.DriverIncentive{}, &models.Driver{})
+	if err := runSchemaMigrations(DB); err != nil {
+		log.Fatal("Failed to migrate database:", err)
+	}
+	err := DB.AutoMigrate(migrationModels()...)
 	if err != nil {
 		log.Fatal("Failed to migrate database:", err)
 	}
+	if err := runDataMigrations(DB); err != nil {
+		log.Fatal("Failed to migrate database:", err)
+	}
 	fmt.Println("Database migration completed")
 }
 
 // MigrateWithFeedback performs database migration and returns an error instead of fatal
 func MigrateWithFeedback() error {
-	err := DB.AutoMigrate(&models.Customer{}, &models.Car{}, &models.Booking{}, &models.Membership{},
-		&models.BookingType{}, &models.DriverIncentive{}, &models.Driver{})
+	if err := runSchemaMigrations(DB); err != nil {
+		return err
+	}
+	err := DB.AutoMigrate(migrationModels()...)
 	if err != nil {
 		return fmt.Errorf("failed to migrate database: %w", err)
 	}
+	if err := runDataMigrations(DB); err != nil {
+		return err
+	}
 	fmt.Println("Database migration completed")
 	return nil
 }
@@ -0,0 +1,166 @@
+package database
+
+import (
+	"car-rental/pkg/models"
+	"car-rental/pkg/money"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// dataMigration is a one-off schema or data fix, recorded in schema_migrations once applied
+type dataMigration struct {
+	Name string
+	Run  func(tx *gorm.DB) error
+}
+
+// schemaMigrations run before AutoMigrate, for changes it would get wrong or not make at all
+var schemaMigrations = []dataMigration{
+	{
+		// Money used to be stored in float columns. Convert them explicitly, rounding each
+		// value to the column's scale, instead of leaving the cast to AutoMigrate.
+		Name: "2026_convert_money_columns",
+		Run:  convertMoneyColumns,
+	},
+}
+
+// dataMigrations are applied in order, each exactly once per database
+var dataMigrations = []dataMigration{
+	{
+		// Stock used to be decremented for every unfinished booking. It now holds the
+		// fleet size, so give back the units that were taken by open bookings.
+		Name: "2026_restore_car_fleet_stock",
+		Run: func(tx *gorm.DB) error {
+			return tx.Exec(`UPDATE cars SET stock = stock + (
+				SELECT COUNT(*) FROM bookings WHERE bookings.cars_id = cars.no AND bookings.finished = false
+			)`).Error
+		},
+	},
+	{
+		// Bookings finished before the status column existed are settled history
+		Name: "2026_backfill_booking_status",
+		Run: func(tx *gorm.DB) error {
+			return tx.Exec("UPDATE bookings SET status = ? WHERE finished = ?", models.BOOKING_STATUS_CLOSED, true).Error
+		},
+	},
+	{
+		// The version behind each ETag is bumped by a trigger, so every writer (handlers,
+		// scheduled jobs, cascades) invalidates copies clients have read. Updates that change
+		// nothing keep the version.
+		Name: "2026_bump_row_versions",
+		Run: func(tx *gorm.DB) error {
+			err := tx.Exec(`CREATE OR REPLACE FUNCTION bump_row_version() RETURNS trigger AS $$
+				BEGIN
+					NEW.version := OLD.version;
+					IF NEW IS DISTINCT FROM OLD THEN
+						NEW.version := OLD.version + 1;
+					END IF;
+					RETURN NEW;
+				END
+				$$ LANGUAGE plpgsql`).Error
+			if err != nil {
+				return err
+			}
+
+			for _, table := range []string{"cars", "customers", "drivers", "bookings"} {
+				err := tx.Exec(fmt.Sprintf(
+					"CREATE TRIGGER %s_bump_version BEFORE UPDATE ON %s FOR EACH ROW EXECUTE FUNCTION bump_row_version()",
+					table, table,
+				)).Error
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+}
+
+// moneyColumnScales maps the column types of money values to their decimal places
+var moneyColumnScales = map[string]int{
+	money.AMOUNT_COLUMN_TYPE: 2,
+	money.RATE_COLUMN_TYPE:   4,
+}
+
+// convertMoneyColumns turns every existing money column that is not numeric yet into its
+// exact numeric type. Tables that don't exist yet are created numeric by AutoMigrate.
+func convertMoneyColumns(tx *gorm.DB) error {
+	for _, model := range migrationModels() {
+		stmt := &gorm.Statement{DB: tx}
+		if err := stmt.Parse(model); err != nil {
+			return err
+		}
+		table := stmt.Schema.Table
+		if !tx.Migrator().HasTable(table) {
+			continue
+		}
+
+		columnTypes, err := tx.Migrator().ColumnTypes(table)
+		if err != nil {
+			return err
+		}
+		existing := make(map[string]string, len(columnTypes))
+		for _, columnType := range columnTypes {
+			existing[columnType.Name()] = columnType.DatabaseTypeName()
+		}
+
+		for _, field := range stmt.Schema.Fields {
+			scale, isMoney := moneyColumnScales[string(field.DataType)]
+			current, exists := existing[field.DBName]
+			if !isMoney || !exists || current == "numeric" {
+				continue
+			}
+
+			err := tx.Exec(fmt.Sprintf(`ALTER TABLE "%s" ALTER COLUMN "%s" TYPE %s USING round("%s"::numeric, %d)`,
+				table, field.DBName, field.DataType, field.DBName, scale)).Error
+			if err != nil {
+				return fmt.Errorf("convert %s.%s: %w", table, field.DBName, err)
+			}
+			log.Printf("Converted %s.%s from %s to %s", table, field.DBName, current, field.DataType)
+		}
+	}
+	return nil
+}
+
+// runSchemaMigrations applies every schema migration that has not been recorded yet
+func runSchemaMigrations(db *gorm.DB) error {
+	return applyMigrations(db, schemaMigrations, "schema")
+}
+
+// runDataMigrations applies every data migration that has not been recorded yet
+func runDataMigrations(db *gorm.DB) error {
+	return applyMigrations(db, dataMigrations, "data")
+}
+
+// applyMigrations runs each migration that schema_migrations has no record of, in order,
+// and records it in the same transaction
+func applyMigrations(db *gorm.DB, migrations []dataMigration, kind string) error {
+	if err := db.AutoMigrate(&models.SchemaMigration{}); err != nil {
+		return fmt.Errorf("failed to migrate schema_migrations table: %w", err)
+	}
+
+	for _, migration := range migrations {
+		var count int64
+		if err := db.Model(&models.SchemaMigration{}).Where("name = ?", migration.Name).Count(&count).Error; err != nil {
+			return fmt.Errorf("failed to check %s migration %s: %w", kind, migration.Name, err)
+		}
+		if count > 0 {
+			continue
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := migration.Run(tx); err != nil {
+				return err
+			}
+			return tx.Create(&models.SchemaMigration{Name: migration.Name, AppliedAt: time.Now()}).Error
+		})
+		if err != nil {
+			return fmt.Errorf("failed to apply %s migration %s: %w", kind, migration.Name, err)
+		}
+		log.Printf("Applied %s migration: %s", kind, migration.Name)
+	}
+
+	return nil
+}
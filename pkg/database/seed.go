@@ -1,11 +1,17 @@
 package database
 
 import (
+	"car-rental/pkg/auth"
 	"car-rental/pkg/models"
+	"car-rental/pkg/money"
+	"errors"
 	"log"
+	"os"
+	"strings"
 )
 
-// SeedData initializes the database with default memberships and booking types
+// SeedData initializes the database with default memberships, booking types, cancellation policy tiers,
+// tax rates and the first admin user
 func SeedData() {
 	log.Println("Checking if database needs seeding...")
 
@@ -19,15 +25,15 @@ func SeedData() {
 		memberships := []models.Membership{
 			{
 				MembershipName: "Bronze",
-				Discount:       4.0, // 4%
+				Discount:       money.RateOf(4), // 4%
 			},
 			{
 				MembershipName: "Silver",
-				Discount:       7.0, // 7%
+				Discount:       money.RateOf(7), // 7%
 			},
 			{
 				MembershipName: "Gold",
-				Discount:       15.0, // 15%
+				Discount:       money.RateOf(15), // 15%
 			},
 		}
 
@@ -71,5 +77,118 @@ func SeedData() {
 		log.Printf("Booking types table already has %d records, skipping seed", bookingTypeCount)
 	}
 
+	// Check if cancellation policy tiers table is empty
+	var tierCount int64
+	DB.Model(&models.CancellationPolicyTier{}).Count(&tierCount)
+
+	if tierCount == 0 {
+		log.Println("Seeding cancellation policy tiers...")
+		// Seed cancellation policy tiers
+		tiers := []models.CancellationPolicyTier{
+			{
+				MinHoursBeforeStart: 168,
+				FeePercent:          money.RateOf(0),
+				Description:         "More than 7 days before pickup",
+			},
+			{
+				MinHoursBeforeStart: 48,
+				FeePercent:          money.RateOf(25),
+				Description:         "2 to 7 days before pickup",
+			},
+			{
+				MinHoursBeforeStart: 24,
+				FeePercent:          money.RateOf(50),
+				Description:         "Within 48 hours of pickup",
+			},
+			{
+				MinHoursBeforeStart: 0,
+				FeePercent:          money.RateOf(100),
+				Description:         "Same day as pickup",
+			},
+		}
+
+		for _, tier := range tiers {
+			if err := DB.Create(&tier).Error; err != nil {
+				log.Printf("Error creating cancellation policy tier %s: %v", tier.Description, err)
+			} else {
+				log.Printf("Created cancellation policy tier: %s (%v%% fee)", tier.Description, tier.FeePercent)
+			}
+		}
+	} else {
+		log.Printf("Cancellation policy tiers table already has %d records, skipping seed", tierCount)
+	}
+
+	// Check if tax rates table is empty. PPN is only seeded on a fresh database: on one that
+	// already holds bookings it would start taxing every new booking and fee unannounced.
+	var taxRateCount, bookingCount int64
+	DB.Model(&models.TaxRate{}).Count(&taxRateCount)
+	DB.Model(&models.Booking{}).Count(&bookingCount)
+
+	if taxRateCount == 0 && bookingCount > 0 {
+		log.Printf("Bookings table already has %d records, skipping tax rate seed; add tax rates through the API", bookingCount)
+	} else if taxRateCount == 0 {
+		log.Println("Seeding tax rates...")
+		// Indonesian VAT, charged on the discounted price
+		ppn := models.TaxRate{
+			Name:            "PPN",
+			Rate:            money.RateOf(11),
+			AppliesToRental: true,
+			AppliesToDriver: true,
+			AppliesToFees:   true,
+		}
+		if err := DB.Create(&ppn).Error; err != nil {
+			log.Printf("Error creating tax rate %s: %v", ppn.Name, err)
+		} else {
+			log.Printf("Created tax rate: %s (%v%%)", ppn.Name, ppn.Rate)
+		}
+	} else {
+		log.Printf("Tax rates table already has %d records, skipping seed", taxRateCount)
+	}
+
+	// Check if an active admin exists. Existing accounts are never promoted: without an
+	// admin, the one named in the environment is created, there is no default password.
+	var adminCount int64
+	DB.Model(&models.User{}).Where("role = ? AND active = ?", models.ROLE_ADMIN, true).Count(&adminCount)
+
+	if adminCount == 0 {
+		password := os.Getenv("ADMIN_PASSWORD")
+		if password == "" {
+			log.Println("No admin user exists and ADMIN_PASSWORD is not set, skipping admin user seed")
+		} else if len(password) < 8 {
+			log.Println("ADMIN_PASSWORD must be at least 8 characters, skipping admin user seed")
+		} else {
+			log.Println("Seeding admin user...")
+			admin := models.User{
+				Username: strings.ToLower(os.Getenv("ADMIN_USERNAME")),
+				Name:     "Administrator",
+				Role:     models.ROLE_ADMIN,
+				Active:   true,
+			}
+			if admin.Username == "" {
+				admin.Username = "admin"
+			}
+
+			// A taken username is refused rather than promoted
+			var existing int64
+			DB.Model(&models.User{}).Where("username = ?", admin.Username).Count(&existing)
+
+			hash, err := auth.HashPassword(password)
+			if err == nil && existing > 0 {
+				err = errors.New("the username is taken, set ADMIN_USERNAME to a new one")
+			}
+			if err == nil {
+				admin.PasswordHash = hash
+				err = DB.Create(&admin).Error
+			}
+			if err != nil {
+				log.Printf("Error creating admin user %s: %v", admin.Username, err)
+			} else {
+				log.Printf("Created admin user: %s", admin.Username)
+			}
+		}
+	} else {
+		log.Printf("Users table already has %d active admins, skipping admin user seed", adminCount)
+	}
+
 	log.Println("Database seeding completed")
 }
@@ -0,0 +1,166 @@
+package gateway
+
+import (
+	"car-rental/pkg/money"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Name and signature header of the fake provider
+const (
+	FAKE_PROVIDER_NAME       = "fake"
+	FAKE_SIGNATURE_HEADER    = "X-Fake-Signature"
+	FAKE_SIGNATURE_PREFIX    = "sha256="
+	FAKE_CHECKOUT_URL_PREFIX = "https://fake-gateway.local/checkout/"
+)
+
+// Webhook secrets shorter than this, or the old published example, are refused
+const (
+	FAKE_MIN_SECRET_LENGTH = 32
+	FAKE_EXAMPLE_SECRET    = "fake-gateway-secret"
+)
+
+// FakeProvider keeps charges in memory and signs its webhooks with HMAC-SHA256, so the
+// whole online payment flow can run without network access
+type FakeProvider struct {
+	secret []byte
+
+	mu       sync.Mutex
+	charges  map[string]*Charge
+	refunded map[string]money.Amount
+}
+
+// NewFakeProvider returns a fake provider signing its webhooks with secret. Anyone who knows
+// the secret can forge a paid webhook, so it must be private and long enough.
+func NewFakeProvider(secret string) (*FakeProvider, error) {
+	if secret == FAKE_EXAMPLE_SECRET {
+		return nil, errors.New("the fake gateway secret is still the published example value")
+	}
+	if len(secret) < FAKE_MIN_SECRET_LENGTH {
+		return nil, fmt.Errorf("the fake gateway secret must be at least %d characters", FAKE_MIN_SECRET_LENGTH)
+	}
+	return &FakeProvider{secret: []byte(secret), charges: map[string]*Charge{}, refunded: map[string]money.Amount{}}, nil
+}
+
+func (p *FakeProvider) Name() string {
+	return FAKE_PROVIDER_NAME
+}
+
+func (p *FakeProvider) CreateCharge(ctx context.Context, request ChargeRequest) (*Charge, error) {
+	if request.Amount <= 0 {
+		return nil, errors.New("charge amount must be positive")
+	}
+
+	id := "ch_" + randomID()
+	charge := &Charge{
+		ID:          id,
+		Status:      CHARGE_STATUS_PENDING,
+		Amount:      request.Amount,
+		CheckoutURL: FAKE_CHECKOUT_URL_PREFIX + id,
+		CreatedAt:   time.Now(),
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.charges[id] = charge
+
+	copied := *charge
+	return &copied, nil
+}
+
+// Refund pays back part or all of a succeeded charge immediately. Like a real provider it
+// refuses to pay back more than is left on the charge.
+func (p *FakeProvider) Refund(ctx context.Context, chargeID string, amount money.Amount) (*Refund, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	charge, ok := p.charges[chargeID]
+	if !ok {
+		return nil, fmt.Errorf("charge %s not found", chargeID)
+	}
+	if charge.Status != CHARGE_STATUS_SUCCEEDED && charge.Status != CHARGE_STATUS_REFUNDED {
+		return nil, fmt.Errorf("charge %s has not succeeded", chargeID)
+	}
+	if amount <= 0 {
+		return nil, errors.New("refund amount must be positive")
+	}
+	if left := charge.Amount - p.refunded[chargeID]; amount > left {
+		return nil, fmt.Errorf("refund of %s exceeds the %s left on charge %s", amount, left, chargeID)
+	}
+
+	p.refunded[chargeID] += amount
+	charge.Status = CHARGE_STATUS_REFUNDED
+	return &Refund{ID: "re_" + randomID(), ChargeID: chargeID, Amount: amount, Status: CHARGE_STATUS_SUCCEEDED}, nil
+}
+
+func (p *FakeProvider) ParseWebhook(payload []byte, header http.Header) (*Event, error) {
+	signature := strings.TrimPrefix(header.Get(FAKE_SIGNATURE_HEADER), FAKE_SIGNATURE_PREFIX)
+	expected, err := hex.DecodeString(signature)
+	if err != nil || !hmac.Equal(expected, p.sign(payload)) {
+		return nil, ErrInvalidSignature
+	}
+
+	var event Event
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// Complete settles a pending charge as the customer would on the checkout page and returns the
+// signed webhook the provider sends for it
+func (p *FakeProvider) Complete(chargeID string, succeeded bool) ([]byte, http.Header, error) {
+	p.mu.Lock()
+	charge, ok := p.charges[chargeID]
+	if !ok {
+		p.mu.Unlock()
+		return nil, nil, fmt.Errorf("charge %s not found", chargeID)
+	}
+	if charge.Status != CHARGE_STATUS_PENDING {
+		p.mu.Unlock()
+		return nil, nil, fmt.Errorf("charge %s is already %s", chargeID, charge.Status)
+	}
+
+	event := Event{ID: "evt_" + randomID(), ChargeID: chargeID, Amount: charge.Amount, OccurredAt: time.Now()}
+	if succeeded {
+		charge.Status = CHARGE_STATUS_SUCCEEDED
+		event.Type = EVENT_CHARGE_SUCCEEDED
+	} else {
+		charge.Status = CHARGE_STATUS_FAILED
+		event.Type = EVENT_CHARGE_FAILED
+	}
+	p.mu.Unlock()
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	header := http.Header{}
+	header.Set(FAKE_SIGNATURE_HEADER, FAKE_SIGNATURE_PREFIX+hex.EncodeToString(p.sign(payload)))
+	return payload, header, nil
+}
+
+func (p *FakeProvider) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, p.secret)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+func randomID() string {
+	buf := make([]byte, 12)
+	if _, err := rand.Read(buf); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(buf)
+}
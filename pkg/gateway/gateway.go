@@ -0,0 +1,89 @@
+package gateway
+
+import (
+	"car-rental/pkg/money"
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Charge status constants
+const (
+	CHARGE_STATUS_PENDING   = "pending"
+	CHARGE_STATUS_SUCCEEDED = "succeeded"
+	CHARGE_STATUS_FAILED    = "failed"
+	CHARGE_STATUS_REFUNDED  = "refunded"
+)
+
+// Webhook event type constants
+const (
+	EVENT_CHARGE_SUCCEEDED = "charge.succeeded"
+	EVENT_CHARGE_FAILED    = "charge.failed"
+)
+
+// ErrInvalidSignature is returned when a webhook was not signed by the provider
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// ChargeRequest asks a provider to collect money for a booking
+type ChargeRequest struct {
+	BookingID   int
+	Amount      money.Amount
+	Description string
+}
+
+// Charge is a payment the customer completes on the provider's side
+type Charge struct {
+	ID          string       `json:"id"`
+	Status      string       `json:"status"`
+	Amount      money.Amount `json:"amount"`
+	CheckoutURL string       `json:"checkout_url"`
+	CreatedAt   time.Time    `json:"created_at"`
+}
+
+// Refund is money sent back through the provider
+type Refund struct {
+	ID       string       `json:"id"`
+	ChargeID string       `json:"charge_id"`
+	Amount   money.Amount `json:"amount"`
+	Status   string       `json:"status"`
+}
+
+// Event is a verified asynchronous callback from a provider
+type Event struct {
+	ID         string       `json:"id"`
+	Type       string       `json:"type"`
+	ChargeID   string       `json:"charge_id"`
+	Amount     money.Amount `json:"amount"`
+	OccurredAt time.Time    `json:"occurred_at"`
+}
+
+// Provider is a payment gateway. Charges complete asynchronously and are reported through
+// signed webhooks, which ParseWebhook verifies before anything is trusted.
+type Provider interface {
+	Name() string
+	CreateCharge(ctx context.Context, request ChargeRequest) (*Charge, error)
+	Refund(ctx context.Context, chargeID string, amount money.Amount) (*Refund, error)
+	ParseWebhook(payload []byte, header http.Header) (*Event, error)
+}
+
+var (
+	mu        sync.RWMutex
+	providers = map[string]Provider{}
+)
+
+// Register makes a provider available under its name
+func Register(provider Provider) {
+	mu.Lock()
+	defer mu.Unlock()
+	providers[provider.Name()] = provider
+}
+
+// Get returns the provider registered under a name
+func Get(name string) (Provider, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	provider, ok := providers[name]
+	return provider, ok
+}
@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"car-rental/pkg/scheduler"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetSchedulerRuns lists the results of the most recent background job runs
+func GetSchedulerRuns(c *gin.Context) {
+	if scheduler.Runner == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Scheduler is not running"})
+		return
+	}
+
+	config := scheduler.Runner.Config()
+	c.JSON(http.StatusOK, gin.H{
+		"data": scheduler.Runner.Runs(),
+		"config": gin.H{
+			"interval":         config.Interval.String(),
+			"reservation_hold": config.ReservationHold.String(),
+		},
+	})
+}
+
+// RunSchedulerNow triggers an immediate run of the background jobs and returns its result
+func RunSchedulerNow(c *gin.Context) {
+	if scheduler.Runner == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Scheduler is not running"})
+		return
+	}
+
+	result := scheduler.Runner.RunOnce(c.Request.Context())
+	c.JSON(http.StatusOK, gin.H{"data": result, "message": "Scheduler run completed"})
+}
@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"car-rental/pkg/auth"
+	"car-rental/pkg/database"
+	"car-rental/pkg/models"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Login checks a username and password and returns an access token and a refresh token
+func Login(c *gin.Context) {
+	var request models.LoginRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Unknown users and wrong passwords get the same answer in the same time: an unknown
+	// user has no hash, which is checked against a dummy one
+	var user models.User
+	err := database.DB.Where("username = ?", strings.ToLower(strings.TrimSpace(request.Username))).First(&user).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign in"})
+		return
+	}
+	if matches := auth.CheckPassword(user.PasswordHash, request.Password); err != nil || !matches {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid username or password"})
+		return
+	}
+
+	if !user.Active {
+		c.JSON(http.StatusForbidden, gin.H{"error": "User account is disabled"})
+		return
+	}
+
+	now := time.Now()
+	var pair *auth.TokenPair
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Update("last_login_at", now).Error; err != nil {
+			return err
+		}
+		pair, _, err = auth.Tokens.IssueTokens(tx, &user, now)
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to issue tokens"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": pair})
+}
+
+// Signup creates a customer and a customer user for them, and signs the new user in
+func Signup(c *gin.Context) {
+	var request models.SignupRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	username := strings.ToLower(strings.TrimSpace(request.Username))
+	var existing int64
+	database.DB.Model(&models.User{}).Where("username = ?", username).Count(&existing)
+	if existing > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Username is already taken"})
+		return
+	}
+
+	// A customer already on file is only linked by staff, so nobody can claim one by its NIK
+	database.DB.Model(&models.Customer{}).Where("nik = ?", request.NIK).Count(&existing)
+	if existing > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "A customer with this NIK is already registered, ask staff to link it to your account"})
+		return
+	}
+
+	hash, err := auth.HashPassword(request.Password)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		return
+	}
+
+	now := time.Now()
+	var pair *auth.TokenPair
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		customer := models.Customer{
+			Name:        request.Name,
+			NIK:         request.NIK,
+			PhoneNumber: request.PhoneNumber,
+		}
+		if err := tx.Create(&customer).Error; err != nil {
+			return err
+		}
+
+		user := models.User{
+			Username:     username,
+			Name:         request.Name,
+			PasswordHash: hash,
+			Role:         models.ROLE_CUSTOMER,
+			CustomerID:   &customer.No,
+			Active:       true,
+			LastLoginAt:  &now,
+		}
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+
+		pair, _, err = auth.Tokens.IssueTokens(tx, &user, now)
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign up"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"data": pair})
+}
+
+// RefreshTokens trades a refresh token for a new access token and refresh token. The old
+// refresh token stops working.
+func RefreshTokens(c *gin.Context) {
+	var request models.RefreshRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	pair, err := auth.Tokens.Refresh(database.DB, request.RefreshToken, time.Now())
+	if errors.Is(err, auth.ErrRefreshTokenReused) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token was already used, all sessions of this user have been signed out"})
+		return
+	}
+	if errors.Is(err, auth.ErrInvalidRefreshToken) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired refresh token"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh tokens"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": pair})
+}
+
+// Logout revokes a refresh token. Access tokens already issued stay valid until they expire.
+func Logout(c *gin.Context) {
+	var request models.RefreshRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := auth.RevokeRefreshToken(database.DB, request.RefreshToken, time.Now()); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign out"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Signed out successfully"})
+}
@@ -1,14 +1,19 @@
 package handlers
 
 import (
+	"car-rental/pkg/auth"
 	"car-rental/pkg/database"
 	"car-rental/pkg/models"
+	"car-rental/pkg/pricing"
 	"car-rental/pkg/utils"
+	"fmt"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // Booking type constants
@@ -17,18 +22,63 @@ const (
 	BOOKING_TYPE_CAR_DRIVER = "Car & Driver"
 )
 
+// bookingWithRelations preloads every relationship returned in booking responses
+func bookingWithRelations(db *gorm.DB) *gorm.DB {
+	return db.Preload("Customer").Preload("Customer.Membership").Preload("Car").Preload("Driver").Preload("CarUnit").Preload("BookingType")
+}
+
 func GetBookings(c *gin.Context) {
+	rate, ok := requestedExchangeRate(c)
+	if !ok {
+		return
+	}
+
+	query := bookingWithRelations(database.DB).Order("no")
+	if auth.OwnRecordsOnly(c) {
+		query = ownBookings(c, query)
+	}
+
 	var bookings []models.Booking
-	result := database.DB.Preload("Customer").Preload("Customer.Membership").Preload("Car").Preload("Driver").Preload("BookingType").Order("no").Find(&bookings)
+	result := query.Find(&bookings)
 
 	if result.Error != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve bookings"})
 		return
 	}
 
+	if rate != nil {
+		for i := range bookings {
+			utils.ConvertBooking(&bookings[i], rate)
+		}
+	}
+
 	c.JSON(http.StatusOK, gin.H{"data": bookings})
 }
 
+// ownBookings limits a booking query to the driver or customer linked to the signed-in user
+func ownBookings(c *gin.Context, query *gorm.DB) *gorm.DB {
+	claims := auth.CurrentClaims(c)
+	switch {
+	case claims.Role == models.ROLE_DRIVER && claims.DriverID != nil:
+		return query.Where("driver_id = ?", *claims.DriverID)
+	case claims.Role == models.ROLE_CUSTOMER && claims.CustomerID != nil:
+		return query.Where("customer_id = ?", *claims.CustomerID)
+	}
+	return query.Where("1 = 0")
+}
+
+// isOwnBooking reports whether a booking belongs to the driver or customer linked to the signed-in user
+func isOwnBooking(c *gin.Context, booking *models.Booking) bool {
+	claims := auth.CurrentClaims(c)
+	switch claims.Role {
+	case models.ROLE_DRIVER:
+		return claims.DriverID != nil && booking.DriverID != nil && *booking.DriverID == *claims.DriverID
+	case models.ROLE_CUSTOMER:
+		return claims.CustomerID != nil && booking.CustomerID == *claims.CustomerID
+	}
+	return false
+}
+
 // Helper function to find a booking by ID
 func findBookingByID(c *gin.Context) (*models.Booking, int, error) {
 	id := c.Param("id")
@@ -38,7 +88,7 @@ func findBookingByID(c *gin.Context) (*models.Booking, int, error) {
 	}
 
 	var booking models.Booking
-	result := database.DB.Preload("Customer").Preload("Customer.Membership").Preload("Car").Preload("Driver").Preload("BookingType").First(&booking, bookingID)
+	result := bookingWithRelations(database.DB).First(&booking, bookingID)
 	if result.Error != nil {
 		return nil, http.StatusNotFound, result.Error
 	}
@@ -57,6 +107,20 @@ func GetBooking(c *gin.Context) {
 		return
 	}
 
+	if auth.OwnRecordsOnly(c) && !isOwnBooking(c, booking) {
+		auth.RespondNotOwnRecord(c, "booking", booking.No)
+		return
+	}
+
+	rate, ok := requestedExchangeRate(c)
+	if !ok {
+		return
+	}
+	if rate != nil {
+		utils.ConvertBooking(booking, rate)
+	}
+
+	utils.SetETag(c, booking.Version)
 	c.JSON(http.StatusOK, gin.H{"data": booking})
 }
 
@@ -68,79 +132,82 @@ func CreateBooking(c *gin.Context) {
 		return
 	}
 
-	// Validate that customer exists
-	var customer models.Customer
-	if err := database.DB.Where("deleted_at IS NULL").Preload("Membership").First(&customer, booking.CustomerID).Error; err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Customer not found or has been removed"})
-		return
-	}
+	// Only the request fields come from the client
+	createBooking(c, models.BookingRequest{
+		CustomerID:    booking.CustomerID,
+		CarsID:        booking.CarsID,
+		StartRent:     booking.StartRent,
+		EndRent:       booking.EndRent,
+		BookingTypeID: booking.BookingTypeID,
+		DriverID:      booking.DriverID,
+		PromoCode:     booking.PromoCode,
+	})
+}
 
-	// Validate that car exists, is not soft-deleted and is available
-	var car models.Car
-	if err := database.DB.Where("deleted_at IS NULL").First(&car, booking.CarsID).Error; err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Car not found or has been removed"})
+// createBooking validates, prices and stores a booking request
+func createBooking(c *gin.Context, request models.BookingRequest) {
+	bookingCtx, status, message := loadBookingRequest(&request)
+	if status != http.StatusOK {
+		c.JSON(status, gin.H{"error": message})
 		return
 	}
+	car := bookingCtx.Car
 
-	if car.Stock <= 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Car is not available for booking"})
+	rate, ok := requestedExchangeRate(c)
+	if !ok {
 		return
 	}
 
-	// Validate dates
-	if booking.StartRent.After(booking.EndRent) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Start date must be before end date"})
-		return
+	// Every booking starts as a reservation and a physical unit is only pinned at pickup
+	booking := models.Booking{
+		CustomerID:    request.CustomerID,
+		CarsID:        request.CarsID,
+		StartRent:     request.StartRent,
+		EndRent:       request.EndRent,
+		BookingTypeID: request.BookingTypeID,
+		DriverID:      request.DriverID,
+		PartnerID:     request.PartnerID,
+		Status:        models.BOOKING_STATUS_PENDING,
 	}
+	if bookingCtx.Voucher != nil {
+		booking.PromoCode = &bookingCtx.Voucher.Code
+	}
+	quote := bookingCtx.Quote()
+	quote.Apply(&booking)
 
-	if booking.StartRent.Before(time.Now().Truncate(24 * time.Hour)) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Start date cannot be in the past"})
-		return
+	// The rate shown to the customer is kept so the booking and its invoice read the same later
+	if rate != nil {
+		booking.Currency = &rate.Currency
+		booking.ExchangeRateID = &rate.No
+		booking.ExchangeRate = &rate.Rate
+		quote.Convert(rate)
 	}
 
-	// Calculate total cost (days * daily_rent)
-	days := int(booking.EndRent.Sub(booking.StartRent).Hours()/24) + 1
-	booking.TotalCost = float64(days) * car.DailyRent
+	// Start a transaction
+	tx := database.DB.Begin()
 
-	// Calculate membership discount
-	var discount float64 = 0
-	if customer.Membership != nil {
-		discount = booking.TotalCost * (customer.Membership.Discount / 100)
-	}
-	booking.Discount = discount
-	// Validate booking type
-	var bookingType models.BookingType
-	if err := database.DB.First(&bookingType, booking.BookingTypeID).Error; err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Booking type not found"})
+	// Hold the car until the booking is stored so parallel requests can't take the same unit
+	lockedCar, err := utils.LockCar(tx, car.No)
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Car not found or has been removed"})
 		return
 	}
 
-	// Validate driver assignment based on booking type
-	if booking.DriverID != nil && bookingType.BookingType != BOOKING_TYPE_CAR_DRIVER {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Driver can only be assigned for 'Car & Driver' booking type"})
+	// Make sure a unit is free for the whole rental window
+	availability, err := utils.CheckCarAvailability(tx, lockedCar, booking.StartRent, booking.EndRent, 0)
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check car availability"})
 		return
 	}
 
-	if booking.DriverID == nil && bookingType.BookingType == BOOKING_TYPE_CAR_DRIVER {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Driver must be assigned for 'Car & Driver' booking type"})
+	if availability.Available <= 0 {
+		tx.Rollback()
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Car is not available for the selected dates", "details": availability})
 		return
 	}
 
-	// Calculate driver cost if driver is requested
-	var totalDriverCost float64 = 0
-	var driver models.Driver
-	if booking.DriverID != nil {
-		if err := database.DB.Where("deleted_at IS NULL").First(&driver, *booking.DriverID).Error; err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Driver not found or has been removed"})
-			return
-		}
-		totalDriverCost = float64(days) * driver.DailyCost
-	}
-	booking.TotalDriverCost = totalDriverCost
-
-	// Start a transaction
-	tx := database.DB.Begin()
-
 	// Create booking
 	if err := tx.Create(&booking).Error; err != nil {
 		tx.Rollback()
@@ -148,20 +215,25 @@ func CreateBooking(c *gin.Context) {
 		return
 	}
 
-	// Update car stock
-	if err := tx.Model(&car).Update("stock", car.Stock-1).Error; err != nil {
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update car stock"})
-		return
+	if bookingCtx.Voucher != nil {
+		if status, message := redeemVoucher(tx, bookingCtx.Voucher, &booking); status != http.StatusOK {
+			tx.Rollback()
+			c.JSON(status, gin.H{"error": message})
+			return
+		}
 	}
 
 	// Commit transaction
 	tx.Commit()
 
 	// Reload booking with relationships
-	database.DB.Preload("Customer").Preload("Customer.Membership").Preload("Car").Preload("Driver").Preload("BookingType").First(&booking, booking.No)
+	bookingWithRelations(database.DB).First(&booking, booking.No)
+	if rate != nil {
+		utils.ConvertBooking(&booking, rate)
+	}
 
-	c.JSON(http.StatusCreated, gin.H{"data": booking})
+	utils.SetETag(c, booking.Version)
+	c.JSON(http.StatusCreated, gin.H{"data": booking, "price_breakdown": quote})
 }
 
 func UpdateBooking(c *gin.Context) {
@@ -181,17 +253,36 @@ func UpdateBooking(c *gin.Context) {
 		return
 	}
 
-	// Don't allow updating if booking is finished
+	updateBooking(c, booking, updateData)
+}
+
+// updateBooking applies an update to a loaded booking, repricing it when the dates change
+func updateBooking(c *gin.Context, booking *models.Booking, updateData models.BookingUpdate) {
+	if !utils.IfMatch(c, booking.Version) {
+		utils.RespondWithVersionConflict(c, "booking", booking.No, booking.Version)
+		return
+	}
+
+	rate, ok := requestedExchangeRate(c)
+	if !ok {
+		return
+	}
+
+	// Don't allow updating if booking is finished or cancelled
 	if booking.Finished {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot update a finished booking"})
 		return
 	}
 
-	// If updating dates, validate and recalculate cost
-	if updateData.StartRent != nil || updateData.EndRent != nil {
-		startRent := booking.StartRent
-		endRent := booking.EndRent
+	if !models.IsActiveBookingStatus(booking.Status) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot update a booking with status '" + booking.Status + "'"})
+		return
+	}
 
+	// Validate new dates; the cost is recalculated on every update either way
+	datesChanged := updateData.StartRent != nil || updateData.EndRent != nil
+	startRent, endRent := booking.StartRent, booking.EndRent
+	if datesChanged {
 		if updateData.StartRent != nil {
 			startRent = *updateData.StartRent
 		}
@@ -208,46 +299,155 @@ func UpdateBooking(c *gin.Context) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Start date cannot be in the past"})
 			return
 		}
+	}
 
-		// Recalculate total cost with membership and driver considerations
-		var car models.Car
-		var customer models.Customer
-		database.DB.First(&car, booking.CarsID)
-		database.DB.Preload("Membership").First(&customer, booking.CustomerID)
+	// Recalculate total cost with membership and driver considerations
+	var car models.Car
+	var customer models.Customer
+	database.DB.First(&car, booking.CarsID)
+	database.DB.Preload("Membership").First(&customer, booking.CustomerID)
 
-		days := int(endRent.Sub(startRent).Hours()/24) + 1
+	var driver *models.Driver
+	if booking.DriverID != nil {
+		driver = &models.Driver{}
+		database.DB.First(driver, *booking.DriverID)
+	}
 
-		// Total cost is always days * daily_rent
-		totalCost := float64(days) * car.DailyRent
-		updateData.TotalCost = &totalCost
+	if datesChanged {
+		if message := utils.CheckRentalLength(&car, utils.RentalDays(startRent, endRent)); message != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": message})
+			return
+		}
+	}
 
-		// Calculate membership discount
-		var discount float64 = 0
-		if customer.Membership != nil {
-			discount = totalCost * (customer.Membership.Discount / 100)
+	// A redeemed voucher stays on the booking but must still allow the new length
+	var redemption *models.VoucherRedemption
+	var voucher *models.Voucher
+	if booking.PromoCode != nil {
+		redemption = &models.VoucherRedemption{}
+		voucher = &models.Voucher{}
+		if err := database.DB.Where("booking_id = ? AND released_at IS NULL", booking.No).First(redemption).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find voucher redemption"})
+			return
+		}
+		if err := database.DB.First(voucher, redemption.VoucherID).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find voucher"})
+			return
 		}
-		updateData.Discount = &discount
-
-		// Calculate driver cost if driver is assigned
-		var totalDriverCost float64 = 0
-		if booking.DriverID != nil {
-			var driver models.Driver
-			database.DB.First(&driver, *booking.DriverID)
-			totalDriverCost = float64(days) * driver.DailyCost
+		if days := utils.RentalDays(startRent, endRent); datesChanged && days < voucher.MinRentalDays {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Voucher %s requires a rental of at least %d days", voucher.Code, voucher.MinRentalDays)})
+			return
 		}
-		updateData.TotalDriverCost = &totalDriverCost
 	}
 
-	// Update only provided fields
-	result := database.DB.Model(booking).Updates(updateData)
-	if result.Error != nil {
+	rules, err := pricing.LoadRateRules(database.DB, car.No, startRent, endRent)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load rate rules"})
+		return
+	}
+
+	taxRates, err := pricing.LoadTaxRates(database.DB)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load tax rates"})
+		return
+	}
+
+	quote := pricing.Calculate(pricing.Input{
+		Car:        car,
+		Membership: customer.Membership,
+		Driver:     driver,
+		Voucher:    voucher,
+		StartRent:  startRent,
+		EndRent:    endRent,
+		RateRules:  rules,
+		TaxRates:   taxRates,
+	})
+	updateData.TotalCost = &quote.TotalCost
+	updateData.Discount = &quote.Discount
+	updateData.TotalDriverCost = &quote.TotalDriverCost
+	updateData.VoucherDiscount = &quote.VoucherDiscount
+	updateData.RentalTax = &quote.RentalTax
+	updateData.DriverTax = &quote.DriverTax
+
+	tx := database.DB.Begin()
+
+	// New dates are checked with the car locked, the same way a new booking is
+	if datesChanged {
+		lockedCar, err := utils.LockCar(tx, booking.CarsID)
+		if err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Car not found or has been removed"})
+			return
+		}
+
+		// The new window must still fit the fleet, ignoring this booking's own reservation
+		availability, err := utils.CheckCarAvailability(tx, lockedCar, startRent, endRent, booking.No)
+		if err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check car availability"})
+			return
+		}
+
+		if availability.Available <= 0 {
+			tx.Rollback()
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Car is not available for the selected dates", "details": availability})
+			return
+		}
+
+		// A pinned unit must also stay free for the new window
+		if booking.CarUnitID != nil {
+			pinned, err := utils.IsCarUnitPinned(tx, *booking.CarUnitID, startRent, endRent, booking.No)
+			if err != nil {
+				tx.Rollback()
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check car unit assignments"})
+				return
+			}
+			if pinned {
+				tx.Rollback()
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Car unit is already assigned to another booking for these dates"})
+				return
+			}
+		}
+	}
+
+	// Update only provided fields, as long as nobody changed the booking since it was read
+	updated, err := utils.UpdateVersioned(tx, booking, booking.No, booking.Version, updateData)
+	if err != nil {
+		tx.Rollback()
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update booking"})
 		return
 	}
+	if !updated {
+		tx.Rollback()
+		database.DB.First(booking, booking.No)
+		utils.RespondWithVersionConflict(c, "booking", booking.No, booking.Version)
+		return
+	}
+
+	if redemption != nil {
+		if err := tx.Model(redemption).Update("discount", *updateData.VoucherDiscount).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update voucher redemption"})
+			return
+		}
+	}
+
+	tx.Commit()
+
+	// Reload the stored amounts so the payment status matches the new total
+	database.DB.First(booking, booking.No)
+	if _, err := utils.RefreshPaymentStatus(database.DB, booking); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update payment status"})
+		return
+	}
 
 	// Reload booking with relationships
-	database.DB.Preload("Customer").Preload("Customer.Membership").Preload("Car").Preload("Driver").Preload("BookingType").First(booking, booking.No)
+	bookingWithRelations(database.DB).First(booking, booking.No)
+	if rate != nil {
+		utils.ConvertBooking(booking, rate)
+	}
 
+	utils.SetETag(c, booking.Version)
 	c.JSON(http.StatusOK, gin.H{"data": booking})
 }
 
@@ -262,55 +462,68 @@ func DeleteBooking(c *gin.Context) {
 		return
 	}
 
-	// Don't allow deleting finished bookings
-	if booking.Finished {
+	// Delete booking; the car's availability is derived from the remaining bookings
+	tx := database.DB.Begin()
+
+	// Payments and transitions lock the booking too, so the checks below can't go stale
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(booking, booking.No).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
+		return
+	}
+
+	// Only reservations that never got past pending are deleted; anything later is part of
+	// the booking history and has to be cancelled instead
+	if booking.Status != models.BOOKING_STATUS_PENDING {
+		tx.Rollback()
 		details := map[string]interface{}{
 			"booking_id": booking.No,
-			"finished":   booking.Finished,
+			"status":     booking.Status,
 		}
-		utils.RespondWithConstraintError(c, "booking", booking.No, "finished_booking", details)
+		utils.RespondWithConstraintError(c, "booking", booking.No, "not_pending", details)
 		return
 	}
 
-	// Start a transaction
-	tx := database.DB.Begin()
-
-	// Delete booking
-	result := tx.Delete(booking)
-	if result.Error != nil {
+	// Money already moved on this booking, so it has to be cancelled and refunded instead
+	var payments int64
+	tx.Model(&models.Payment{}).Where("booking_id = ?", booking.No).Count(&payments)
+	if payments > 0 {
 		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete booking from database"})
+		details := map[string]interface{}{
+			"booking_id": booking.No,
+			"payments":   payments,
+		}
+		utils.RespondWithConstraintError(c, "booking", booking.No, "has_payments", details)
 		return
 	}
 
-	// Restore car stock
-	var car models.Car
-	if err := tx.First(&car, booking.CarsID).Error; err != nil {
+	// A deleted booking never used its voucher, so its redemption goes with it
+	if err := tx.Where("booking_id = ?", booking.No).Delete(&models.VoucherRedemption{}).Error; err != nil {
 		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find associated car for stock restoration"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to release voucher redemption"})
 		return
 	}
 
-	if err := tx.Model(&car).Update("stock", car.Stock+1).Error; err != nil {
+	result := tx.Delete(booking)
+	if result.Error != nil {
 		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore car stock"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete booking from database"})
 		return
 	}
 
-	// Commit transaction
 	tx.Commit()
 
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Booking deleted successfully and car stock restored",
+		"message": "Booking deleted successfully and its dates released",
 		"details": map[string]interface{}{
 			"deleted_booking_id": booking.No,
-			"restored_car_stock": car.Stock + 1,
+			"released_car_id":    booking.CarsID,
 		},
 	})
 }
 
-// FinishBooking marks a booking as finished and restores car stock
-func FinishBooking(c *gin.Context) {
+// AssignCarUnit pins the physical unit handed to the customer so damage and mileage can be traced
+func AssignCarUnit(c *gin.Context) {
 	booking, status, err := findBookingByID(c)
 	if err != nil {
 		if status == http.StatusBadRequest {
@@ -321,58 +534,90 @@ func FinishBooking(c *gin.Context) {
 		return
 	}
 
-	if booking.Finished {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Booking is already finished"})
+	var assignment models.BookingUnitAssignment
+	if err := c.ShouldBindJSON(&assignment); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Start a transaction
-	tx := database.DB.Begin()
-
-	// Mark booking as finished
-	if err := tx.Model(booking).Update("finished", true).Error; err != nil {
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to finish booking"})
+	if !models.IsActiveBookingStatus(booking.Status) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot assign a unit to a booking with status '" + booking.Status + "'"})
 		return
 	}
 
-	// Restore car stock
-	var car models.Car
-	if err := tx.First(&car, booking.CarsID).Error; err != nil {
+	tx := database.DB.Begin()
+	if status, message := pinCarUnit(tx, booking, assignment.CarUnitID); status != http.StatusOK {
 		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find car"})
+		c.JSON(status, gin.H{"error": message})
 		return
 	}
+	tx.Commit()
 
-	if err := tx.Model(&car).Update("stock", car.Stock+1).Error; err != nil {
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore car stock"})
-		return
+	// Reload booking with relationships
+	bookingWithRelations(database.DB).First(booking, booking.No)
+
+	c.JSON(http.StatusOK, gin.H{"data": booking, "message": "Car unit assigned successfully"})
+}
+
+// pinCarUnit validates that the unit can be handed out for the booking and stores it.
+// It returns http.StatusOK on success, or the status and message to respond with. The
+// unit stays locked until db's transaction ends, so it can't be pinned twice at once.
+func pinCarUnit(db *gorm.DB, booking *models.Booking, unitID int) (int, string) {
+	var unit models.CarUnit
+	if err := db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("deleted_at IS NULL").First(&unit, unitID).Error; err != nil {
+		return http.StatusBadRequest, "Car unit not found or has been removed"
 	}
 
-	// Calculate and save driver incentive if driver was assigned
-	if booking.DriverID != nil {
-		days := int(booking.EndRent.Sub(booking.StartRent).Hours()/24) + 1
-		baseCost := float64(days) * car.DailyRent
-		incentive := baseCost * 0.05 // 5% of base cost (days * daily_rent)
+	if unit.CarID != booking.CarsID {
+		return http.StatusBadRequest, "Car unit does not belong to the booked car"
+	}
 
-		driverIncentive := models.DriverIncentive{
-			BookingID: booking.No,
-			Incentive: incentive,
-		}
+	if unit.Status != models.CAR_UNIT_STATUS_AVAILABLE {
+		return http.StatusBadRequest, "Car unit is not in service (status: " + unit.Status + ")"
+	}
 
-		if err := tx.Create(&driverIncentive).Error; err != nil {
-			tx.Rollback()
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create driver incentive"})
-			return
+	pinned, err := utils.IsCarUnitPinned(db, unit.No, booking.StartRent, booking.EndRent, booking.No)
+	if err != nil {
+		return http.StatusInternalServerError, "Failed to check car unit assignments"
+	}
+	if pinned {
+		return http.StatusBadRequest, "Car unit is already assigned to another booking for these dates"
+	}
+
+	if err := db.Model(booking).Update("car_unit_id", unit.No).Error; err != nil {
+		return http.StatusInternalServerError, "Failed to assign car unit"
+	}
+
+	return http.StatusOK, ""
+}
+
+// FinishBooking is the legacy name of ReturnBooking. It follows the same state machine, so
+// only a picked up booking can be finished, which frees its car for other bookings.
+func FinishBooking(c *gin.Context) {
+	booking, status, err := findBookingByID(c)
+	if err != nil {
+		if status == http.StatusBadRequest {
+			c.JSON(status, gin.H{"error": "Invalid booking ID"})
+		} else {
+			c.JSON(status, gin.H{"error": "Booking not found"})
 		}
+		return
 	}
 
-	// Commit transaction
-	tx.Commit()
+	if booking.Finished {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Booking is already finished"})
+		return
+	}
 
-	// Reload booking with relationships
-	database.DB.Preload("Customer").Preload("Customer.Membership").Preload("Car").Preload("Driver").Preload("BookingType").First(booking, booking.No)
+	if !models.CanTransitionBooking(booking.Status, models.BOOKING_STATUS_RETURNED) {
+		utils.RespondWithTransitionError(c, "booking", booking.No, booking.Status, models.BOOKING_STATUS_RETURNED)
+		return
+	}
+
+	returnedAt, ok := bindReturnedAt(c)
+	if !ok {
+		return
+	}
 
-	c.JSON(http.StatusOK, gin.H{"data": booking, "message": "Booking finished successfully"})
+	applyBookingTransition(c, booking, models.BOOKING_STATUS_RETURNED, completeReturnAt(returnedAt), "Booking finished successfully")
 }
@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"car-rental/pkg/database"
+	"car-rental/pkg/models"
+	"car-rental/pkg/pricing"
+	"car-rental/pkg/utils"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// bookingRequestContext holds the records a booking request refers to once they are validated
+type bookingRequestContext struct {
+	Request     *models.BookingRequest
+	Customer    models.Customer
+	Car         models.Car
+	BookingType models.BookingType
+	Driver      *models.Driver
+	Voucher     *models.Voucher
+	RateRules   []models.RateRule
+	TaxRates    []models.TaxRate
+}
+
+// Quote prices the request with the same rules used when the booking is stored
+func (b *bookingRequestContext) Quote() pricing.Quote {
+	return pricing.Calculate(pricing.Input{
+		Car:        b.Car,
+		Membership: b.Customer.Membership,
+		Driver:     b.Driver,
+		Voucher:    b.Voucher,
+		StartRent:  b.Request.StartRent,
+		EndRent:    b.Request.EndRent,
+		RateRules:  b.RateRules,
+		TaxRates:   b.TaxRates,
+	})
+}
+
+// loadBookingRequest validates a booking request and loads the records it refers to. It returns
+// http.StatusOK on success, or the status and message to respond with.
+func loadBookingRequest(request *models.BookingRequest) (*bookingRequestContext, int, string) {
+	bookingCtx := &bookingRequestContext{Request: request}
+
+	// Validate that customer exists
+	if err := database.DB.Where("deleted_at IS NULL").Preload("Membership").First(&bookingCtx.Customer, request.CustomerID).Error; err != nil {
+		return nil, http.StatusBadRequest, "Customer not found or has been removed"
+	}
+
+	// Validate that car exists and is not soft-deleted
+	if err := database.DB.Where("deleted_at IS NULL").First(&bookingCtx.Car, request.CarsID).Error; err != nil {
+		return nil, http.StatusBadRequest, "Car not found or has been removed"
+	}
+
+	// Validate dates
+	if request.StartRent.After(request.EndRent) {
+		return nil, http.StatusBadRequest, "Start date must be before end date"
+	}
+
+	if request.StartRent.Before(time.Now().Truncate(24 * time.Hour)) {
+		return nil, http.StatusBadRequest, "Start date cannot be in the past"
+	}
+
+	// Validate the rental length against the car's limits
+	if message := utils.CheckRentalLength(&bookingCtx.Car, utils.RentalDays(request.StartRent, request.EndRent)); message != "" {
+		return nil, http.StatusBadRequest, message
+	}
+
+	// Validate booking type
+	if err := database.DB.First(&bookingCtx.BookingType, request.BookingTypeID).Error; err != nil {
+		return nil, http.StatusBadRequest, "Booking type not found"
+	}
+
+	// Validate driver assignment based on booking type
+	if request.DriverID != nil && bookingCtx.BookingType.BookingType != BOOKING_TYPE_CAR_DRIVER {
+		return nil, http.StatusBadRequest, "Driver can only be assigned for 'Car & Driver' booking type"
+	}
+
+	if request.DriverID == nil && bookingCtx.BookingType.BookingType == BOOKING_TYPE_CAR_DRIVER {
+		return nil, http.StatusBadRequest, "Driver must be assigned for 'Car & Driver' booking type"
+	}
+
+	if request.DriverID != nil {
+		var driver models.Driver
+		if err := database.DB.Where("deleted_at IS NULL").First(&driver, *request.DriverID).Error; err != nil {
+			return nil, http.StatusBadRequest, "Driver not found or has been removed"
+		}
+		bookingCtx.Driver = &driver
+	}
+
+	rules, err := pricing.LoadRateRules(database.DB, request.CarsID, request.StartRent, request.EndRent)
+	if err != nil {
+		return nil, http.StatusInternalServerError, "Failed to load rate rules"
+	}
+	bookingCtx.RateRules = rules
+
+	taxRates, err := pricing.LoadTaxRates(database.DB)
+	if err != nil {
+		return nil, http.StatusInternalServerError, "Failed to load tax rates"
+	}
+	bookingCtx.TaxRates = taxRates
+
+	if request.PromoCode != nil && *request.PromoCode != "" {
+		voucher, err := utils.FindVoucherByCode(database.DB, *request.PromoCode)
+		if err != nil {
+			return nil, http.StatusBadRequest, "Voucher not found"
+		}
+
+		days := utils.RentalDays(request.StartRent, request.EndRent)
+		message, err := utils.CheckVoucher(database.DB, voucher, request.CustomerID, request.CarsID, days, time.Now())
+		if err != nil {
+			return nil, http.StatusInternalServerError, "Failed to check voucher"
+		}
+		if message != "" {
+			return nil, http.StatusBadRequest, message
+		}
+		bookingCtx.Voucher = voucher
+
+		// A voucher that does not stack is dropped when the membership discount is worth more
+		if bookingCtx.Quote().VoucherDiscount == 0 {
+			return nil, http.StatusBadRequest, "Voucher " + voucher.Code + " does not combine with the membership discount, which is already higher"
+		}
+	}
+
+	return bookingCtx, http.StatusOK, ""
+}
+
+// QuoteBooking prices a booking request and reports availability without storing anything
+func QuoteBooking(c *gin.Context) {
+	var request models.BookingRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	quoteBooking(c, request)
+}
+
+// quoteBooking prices a validated booking request together with the car's availability
+func quoteBooking(c *gin.Context, request models.BookingRequest) {
+	bookingCtx, status, message := loadBookingRequest(&request)
+	if status != http.StatusOK {
+		c.JSON(status, gin.H{"error": message})
+		return
+	}
+
+	rate, ok := requestedExchangeRate(c)
+	if !ok {
+		return
+	}
+
+	availability, err := utils.CheckCarAvailability(database.DB, &bookingCtx.Car, request.StartRent, request.EndRent, 0)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check car availability"})
+		return
+	}
+
+	quote := bookingCtx.Quote()
+	if rate != nil {
+		quote.Convert(rate)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": quote, "availability": availability})
+}
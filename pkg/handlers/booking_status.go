@@ -0,0 +1,286 @@
+package handlers
+
+import (
+	"car-rental/pkg/database"
+	"car-rental/pkg/models"
+	"car-rental/pkg/money"
+	"car-rental/pkg/pricing"
+	"car-rental/pkg/utils"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Share of the rent charged that a driver earns as incentive (5%)
+const DRIVER_INCENTIVE_PERCENT money.Rate = 5 * money.RATE_SCALE
+
+// bookingSideEffect runs inside the transition transaction. It returns http.StatusOK on
+// success, or the status and message to respond with after rolling back.
+type bookingSideEffect func(tx *gorm.DB, booking *models.Booking) (int, string)
+
+// ConfirmBooking moves a reservation to confirmed once the deposit is received
+func ConfirmBooking(c *gin.Context) {
+	transitionBooking(c, models.BOOKING_STATUS_CONFIRMED, nil, "Booking confirmed successfully")
+}
+
+// PickUpBooking records that the vehicle left the branch, optionally pinning the unit handed out
+func PickUpBooking(c *gin.Context) {
+	var pickup models.BookingPickup
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&pickup); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	var sideEffect bookingSideEffect
+	if pickup.CarUnitID != nil {
+		sideEffect = func(tx *gorm.DB, booking *models.Booking) (int, string) {
+			return pinCarUnit(tx, booking, *pickup.CarUnitID)
+		}
+	}
+
+	transitionBooking(c, models.BOOKING_STATUS_PICKED_UP, sideEffect, "Booking picked up successfully")
+}
+
+// ReturnBooking records that the vehicle is back, freeing the car for other bookings
+func ReturnBooking(c *gin.Context) {
+	returnedAt, ok := bindReturnedAt(c)
+	if !ok {
+		return
+	}
+
+	transitionBooking(c, models.BOOKING_STATUS_RETURNED, completeReturnAt(returnedAt), "Booking returned successfully")
+}
+
+// bindReturnedAt reads the optional actual return time, defaulting to now
+func bindReturnedAt(c *gin.Context) (time.Time, bool) {
+	var bookingReturn models.BookingReturn
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&bookingReturn); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return time.Time{}, false
+		}
+	}
+
+	now := time.Now()
+	if bookingReturn.ReturnedAt == nil {
+		return now, true
+	}
+
+	if bookingReturn.ReturnedAt.After(now) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Return time cannot be in the future"})
+		return time.Time{}, false
+	}
+
+	return *bookingReturn.ReturnedAt, true
+}
+
+// CloseBooking marks a returned booking as settled. It is refused while money is owed either way.
+func CloseBooking(c *gin.Context) {
+	transitionBooking(c, models.BOOKING_STATUS_CLOSED, requireSettledBalance, "Booking closed successfully")
+}
+
+// CancelBooking cancels a reservation that has not been picked up yet. The row is kept,
+// the reason recorded and a fee charged according to the cancellation policy tiers.
+func CancelBooking(c *gin.Context) {
+	var cancellation models.BookingCancellation
+	if err := c.ShouldBindJSON(&cancellation); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	transitionBooking(c, models.BOOKING_STATUS_CANCELLED, cancelWithReason(cancellation.Reason), "Booking cancelled successfully")
+}
+
+// cancelWithReason is the side effect of the cancel transition
+func cancelWithReason(reason string) bookingSideEffect {
+	return func(tx *gorm.DB, booking *models.Booking) (int, string) {
+		return recordCancellation(tx, booking, reason, time.Now())
+	}
+}
+
+// recordCancellation stores the reason and the fee due for cancelling at the given moment
+func recordCancellation(tx *gorm.DB, booking *models.Booking, reason string, at time.Time) (int, string) {
+	charge, err := utils.CalculateCancellationCharge(tx, booking, at)
+	if err != nil {
+		return http.StatusInternalServerError, "Failed to calculate cancellation fee"
+	}
+
+	// The fee is taxed at the rates in force when the booking is cancelled
+	taxRates, err := pricing.LoadTaxRates(tx)
+	if err != nil {
+		return http.StatusInternalServerError, "Failed to load tax rates"
+	}
+	feeTax := pricing.TaxTotal(pricing.CalculateTaxes(taxRates, pricing.TAX_BASE_FEES, charge.Fee, 0))
+
+	updates := map[string]interface{}{
+		"cancelled_at":             at,
+		"cancellation_reason":      reason,
+		"cancellation_fee_percent": charge.FeePercent,
+		"cancellation_fee":         charge.Fee,
+		"cancellation_fee_tax":     feeTax,
+	}
+	if err := tx.Model(booking).Updates(updates).Error; err != nil {
+		return http.StatusInternalServerError, "Failed to record cancellation"
+	}
+
+	// The voucher use is given back so the customer can book again with it
+	if err := utils.ReleaseVoucherRedemptions(tx, []int{booking.No}, at); err != nil {
+		return http.StatusInternalServerError, "Failed to release voucher redemption"
+	}
+
+	return http.StatusOK, ""
+}
+
+// transitionBooking loads the booking in the URL and moves it to the given status if the
+// state machine allows it
+func transitionBooking(c *gin.Context, to string, sideEffect bookingSideEffect, message string) {
+	booking, status, err := findBookingByID(c)
+	if err != nil {
+		if status == http.StatusBadRequest {
+			c.JSON(status, gin.H{"error": "Invalid booking ID"})
+		} else {
+			c.JSON(status, gin.H{"error": "Booking not found"})
+		}
+		return
+	}
+
+	if !models.CanTransitionBooking(booking.Status, to) {
+		utils.RespondWithTransitionError(c, "booking", booking.No, booking.Status, to)
+		return
+	}
+
+	applyBookingTransition(c, booking, to, sideEffect, message)
+}
+
+// applyBookingTransition stores the new status together with its side effects in one transaction
+func applyBookingTransition(c *gin.Context, booking *models.Booking, to string, sideEffect bookingSideEffect, message string) {
+	// Start a transaction
+	tx := database.DB.Begin()
+
+	// Lock the booking so two transitions can't both start from the status checked above
+	from := booking.Status
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(booking, booking.No).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to lock booking"})
+		return
+	}
+	if booking.Status != from {
+		tx.Rollback()
+		utils.RespondWithTransitionError(c, "booking", booking.No, booking.Status, to)
+		return
+	}
+
+	if err := setBookingStatus(tx, booking, to); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update booking status"})
+		return
+	}
+
+	if sideEffect != nil {
+		if status, errorMessage := sideEffect(tx, booking); status != http.StatusOK {
+			tx.Rollback()
+			c.JSON(status, gin.H{"error": errorMessage})
+			return
+		}
+	}
+
+	// Fees charged by the transition change what is owed
+	balance, err := utils.RefreshPaymentStatus(tx, booking)
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update payment status"})
+		return
+	}
+
+	// Commit transaction
+	tx.Commit()
+
+	// Reload booking with relationships
+	bookingWithRelations(database.DB).First(booking, booking.No)
+
+	response := gin.H{"data": booking, "message": message}
+	if to == models.BOOKING_STATUS_RETURNED {
+		response["cost_breakdown"] = utils.NewBookingCostBreakdown(booking)
+
+		// The car is back either way, but staff are warned when the customer still owes money
+		response["balance"] = balance
+		if balance.Outstanding > 0 {
+			response["warning"] = fmt.Sprintf("An outstanding balance of %s must be settled before the booking can be closed", balance.Outstanding)
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// setBookingStatus stores a status and keeps the legacy finished flag in sync with it
+func setBookingStatus(tx *gorm.DB, booking *models.Booking, status string) error {
+	finished := status == models.BOOKING_STATUS_RETURNED || status == models.BOOKING_STATUS_CLOSED
+	if err := tx.Model(booking).Updates(map[string]interface{}{"status": status, "finished": finished}).Error; err != nil {
+		return err
+	}
+
+	booking.Status = status
+	booking.Finished = finished
+	return nil
+}
+
+// completeReturnAt records the actual return time, charges any overdue fee, books the
+// driver incentive and issues the invoice once the car is back
+func completeReturnAt(returnedAt time.Time) bookingSideEffect {
+	return func(tx *gorm.DB, booking *models.Booking) (int, string) {
+		return completeReturn(tx, booking, returnedAt)
+	}
+}
+
+func completeReturn(tx *gorm.DB, booking *models.Booking, returnedAt time.Time) (int, string) {
+	var car models.Car
+	if err := tx.First(&car, booking.CarsID).Error; err != nil {
+		return http.StatusInternalServerError, "Failed to find car"
+	}
+
+	// Charge late returns at the car's daily rent scaled by the late-fee multiplier
+	overdue := utils.CalculateOverdueCharge(booking, car.DailyRent, returnedAt, utils.LateFeeMultiplier())
+
+	// Late fees are taxed at the rates in force when the car comes back
+	taxRates, err := pricing.LoadTaxRates(tx)
+	if err != nil {
+		return http.StatusInternalServerError, "Failed to load tax rates"
+	}
+	feeTax := pricing.TaxTotal(pricing.CalculateTaxes(taxRates, pricing.TAX_BASE_FEES, overdue.Fee, 0))
+
+	updates := map[string]interface{}{
+		"returned_at":         returnedAt,
+		"overdue_hours":       overdue.Hours,
+		"late_fee_multiplier": overdue.Multiplier,
+		"overdue_fee":         overdue.Fee,
+		"fee_tax":             feeTax,
+	}
+	if err := tx.Model(booking).Updates(updates).Error; err != nil {
+		return http.StatusInternalServerError, "Failed to record return"
+	}
+
+	// Calculate and save driver incentive if driver was assigned
+	if booking.DriverID != nil {
+		driverIncentive := models.DriverIncentive{
+			BookingID: booking.No,
+			Incentive: booking.TotalCost.Percent(DRIVER_INCENTIVE_PERCENT), // Seasonal rates included
+		}
+
+		if err := tx.Create(&driverIncentive).Error; err != nil {
+			return http.StatusInternalServerError, "Failed to create driver incentive"
+		}
+	}
+
+	// Issue the invoice now that every charge is known
+	if _, err := utils.IssueInvoice(tx, booking, time.Now()); err != nil {
+		return http.StatusInternalServerError, "Failed to issue invoice"
+	}
+
+	return http.StatusOK, ""
+}
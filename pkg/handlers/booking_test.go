@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"bytes"
+	"car-rental/pkg/models"
+	"car-rental/pkg/testutil"
+	"car-rental/pkg/utils"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Parallel requests fired at the one unit of the test car
+const OVERBOOKING_REQUESTS = 20
+
+// bookingRouter serves the staff booking handlers without the auth and quota middleware,
+// which would turn the parallel requests away before they reach the booking code
+func bookingRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/bookings", CreateBooking)
+	r.PUT("/bookings/:id", UpdateBooking)
+	return r
+}
+
+// overbookingFixture books a car with a single unit through the handlers
+type overbookingFixture struct {
+	*testutil.Fixture
+}
+
+func newOverbookingFixture(t *testing.T, db *gorm.DB) *overbookingFixture {
+	t.Helper()
+	return &overbookingFixture{testutil.NewFixture(t, db, "Overbooking test", 1)}
+}
+
+func (f *overbookingFixture) request(start, end time.Time) gin.H {
+	return gin.H{
+		"customer_id":     f.Customer.No,
+		"cars_id":         f.Car.No,
+		"start_rent":      start,
+		"end_rent":        end,
+		"booking_type_id": f.BookingType.No,
+	}
+}
+
+// book stores a booking through the handler and fails the test if it is not created
+func (f *overbookingFixture) book(t *testing.T, r *gin.Engine, start, end time.Time) int {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, jsonRequest(t, http.MethodPost, "/bookings", f.request(start, end)))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create booking for %s: status %d: %s", start.Format("2006-01-02"), w.Code, w.Body.String())
+	}
+
+	var response struct {
+		Data models.Booking `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("decode booking: %v", err)
+	}
+	return response.Data.No
+}
+
+// reserved returns the most units of the test car booked at once within the window
+func (f *overbookingFixture) reserved(t *testing.T, start, end time.Time) int {
+	t.Helper()
+
+	units, err := utils.PeakReservedUnits(f.DB, f.Car.No, start, end, 0)
+	if err != nil {
+		t.Fatalf("count reserved units: %v", err)
+	}
+	return units
+}
+
+// inParallel releases every request at once and counts the responses by status
+func inParallel(r *gin.Engine, requests []*http.Request) map[int]int {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	statuses := make(map[int]int)
+	start := make(chan struct{})
+
+	for _, req := range requests {
+		wg.Add(1)
+		go func(req *http.Request) {
+			defer wg.Done()
+			w := httptest.NewRecorder()
+
+			<-start
+			r.ServeHTTP(w, req)
+
+			mu.Lock()
+			statuses[w.Code]++
+			mu.Unlock()
+		}(req)
+	}
+
+	close(start)
+	wg.Wait()
+	return statuses
+}
+
+// jsonRequest builds a request with a JSON body
+func jsonRequest(t *testing.T, method, path string, body gin.H) *http.Request {
+	t.Helper()
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		t.Fatalf("encode request: %v", err)
+	}
+	req := httptest.NewRequest(method, path, bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	return req
+}
+
+func TestParallelCreatesBookTheLastUnitOnce(t *testing.T) {
+	db := testutil.DB(t)
+	f := newOverbookingFixture(t, db)
+	r := bookingRouter()
+
+	start := time.Now().Truncate(24*time.Hour).AddDate(0, 2, 0)
+	end := start.AddDate(0, 0, 3)
+
+	requests := make([]*http.Request, OVERBOOKING_REQUESTS)
+	for i := range requests {
+		requests[i] = jsonRequest(t, http.MethodPost, "/bookings", f.request(start, end))
+	}
+	statuses := inParallel(r, requests)
+
+	if statuses[http.StatusCreated] != 1 {
+		t.Errorf("%d of %d parallel bookings were created for a single unit, want 1: %v",
+			statuses[http.StatusCreated], OVERBOOKING_REQUESTS, statuses)
+	}
+	if units := f.reserved(t, start, end); units > 1 {
+		t.Errorf("%d units are reserved for a car with 1 unit", units)
+	}
+}
+
+func TestParallelDateMovesAndCreatesBookTheLastUnitOnce(t *testing.T) {
+	db := testutil.DB(t)
+	f := newOverbookingFixture(t, db)
+	r := bookingRouter()
+
+	// Every booking starts on its own week and is then moved onto the same target window
+	// while new bookings ask for that window too
+	base := time.Now().Truncate(24*time.Hour).AddDate(0, 3, 0)
+	target, targetEnd := base, base.AddDate(0, 0, 3)
+
+	moves := OVERBOOKING_REQUESTS / 2
+	requests := make([]*http.Request, 0, OVERBOOKING_REQUESTS)
+	for i := 0; i < moves; i++ {
+		start := base.AddDate(0, 0, 7*(i+1))
+		id := f.book(t, r, start, start.AddDate(0, 0, 2))
+		requests = append(requests, jsonRequest(t, http.MethodPut, fmt.Sprintf("/bookings/%d", id), gin.H{
+			"start_rent": target,
+			"end_rent":   targetEnd,
+		}))
+	}
+	for len(requests) < OVERBOOKING_REQUESTS {
+		requests = append(requests, jsonRequest(t, http.MethodPost, "/bookings", f.request(target, targetEnd)))
+	}
+
+	statuses := inParallel(r, requests)
+
+	if succeeded := statuses[http.StatusOK] + statuses[http.StatusCreated]; succeeded != 1 {
+		t.Errorf("%d of %d parallel moves and bookings got the target window of a single unit, want 1: %v",
+			succeeded, OVERBOOKING_REQUESTS, statuses)
+	}
+	if units := f.reserved(t, target, targetEnd); units > 1 {
+		t.Errorf("%d units are reserved for a car with 1 unit", units)
+	}
+}
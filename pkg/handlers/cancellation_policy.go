@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"car-rental/pkg/database"
+	"car-rental/pkg/models"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+func GetCancellationPolicyTiers(c *gin.Context) {
+	var tiers []models.CancellationPolicyTier
+	result := database.DB.Order("min_hours_before_start DESC").Find(&tiers)
+
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve cancellation policy tiers"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": tiers})
+}
+
+// Helper function to find a cancellation policy tier by ID
+func findCancellationPolicyTierByID(c *gin.Context) (*models.CancellationPolicyTier, int, error) {
+	id := c.Param("id")
+	tierID, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	var tier models.CancellationPolicyTier
+	result := database.DB.First(&tier, tierID)
+	if result.Error != nil {
+		return nil, http.StatusNotFound, result.Error
+	}
+
+	return &tier, http.StatusOK, nil
+}
+
+func GetCancellationPolicyTier(c *gin.Context) {
+	tier, status, err := findCancellationPolicyTierByID(c)
+	if err != nil {
+		if status == http.StatusBadRequest {
+			c.JSON(status, gin.H{"error": "Invalid cancellation policy tier ID"})
+		} else {
+			c.JSON(status, gin.H{"error": "Cancellation policy tier not found"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": tier})
+}
+
+func CreateCancellationPolicyTier(c *gin.Context) {
+	var tier models.CancellationPolicyTier
+
+	if err := c.ShouldBindJSON(&tier); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result := database.DB.Create(&tier)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create cancellation policy tier"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"data": tier})
+}
+
+func UpdateCancellationPolicyTier(c *gin.Context) {
+	tier, status, err := findCancellationPolicyTierByID(c)
+	if err != nil {
+		if status == http.StatusBadRequest {
+			c.JSON(status, gin.H{"error": "Invalid cancellation policy tier ID"})
+		} else {
+			c.JSON(status, gin.H{"error": "Cancellation policy tier not found"})
+		}
+		return
+	}
+
+	var updateData models.CancellationPolicyTier
+	if err := c.ShouldBindJSON(&updateData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// A fee of 0% and a threshold of 0 hours are meaningful, so save every field
+	result := database.DB.Model(tier).Select("min_hours_before_start", "fee_percent", "description").Updates(updateData)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update cancellation policy tier"})
+		return
+	}
+
+	database.DB.First(tier, tier.No)
+
+	c.JSON(http.StatusOK, gin.H{"data": tier})
+}
+
+func DeleteCancellationPolicyTier(c *gin.Context) {
+	tier, status, err := findCancellationPolicyTierByID(c)
+	if err != nil {
+		if status == http.StatusBadRequest {
+			c.JSON(status, gin.H{"error": "Invalid cancellation policy tier ID"})
+		} else {
+			c.JSON(status, gin.H{"error": "Cancellation policy tier not found"})
+		}
+		return
+	}
+
+	// Cancelled bookings keep a copy of the fee they were charged, so tiers can be removed
+	result := database.DB.Delete(tier)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete cancellation policy tier"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Cancellation policy tier deleted successfully"})
+}
@@ -3,14 +3,22 @@ package handlers
 import (
 	"car-rental/pkg/database"
 	"car-rental/pkg/models"
+	"car-rental/pkg/money"
+	"car-rental/pkg/pricing"
 	"car-rental/pkg/utils"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 func GetCars(c *gin.Context) {
+	rate, ok := requestedExchangeRate(c)
+	if !ok {
+		return
+	}
+
 	var cars []models.Car
 	result := database.DB.Where("deleted_at IS NULL").Order("no").Find(&cars)
 
@@ -19,6 +27,16 @@ func GetCars(c *gin.Context) {
 		return
 	}
 
+	inUse, err := utils.UnitsInUseAt(database.DB, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate car availability"})
+		return
+	}
+	utils.SetCurrentAvailability(cars, inUse)
+	if rate != nil {
+		convertCars(cars, rate)
+	}
+
 	c.JSON(http.StatusOK, gin.H{"data": cars})
 }
 
@@ -50,7 +68,123 @@ func GetCar(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"data": car})
+	rate, ok := requestedExchangeRate(c)
+	if !ok {
+		return
+	}
+
+	inUse, err := utils.UnitsInUseAt(database.DB, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate car availability"})
+		return
+	}
+	cars := []models.Car{*car}
+	utils.SetCurrentAvailability(cars, inUse)
+	if rate != nil {
+		convertCars(cars, rate)
+	}
+
+	utils.SetETag(c, car.Version)
+	c.JSON(http.StatusOK, gin.H{"data": cars[0]})
+}
+
+// convertCars shows the rents of each car in the currency of an exchange rate
+func convertCars(cars []models.Car, rate *models.ExchangeRate) {
+	for i := range cars {
+		cars[i].Converted = rate.Convert(cars[i].PriceAmounts())
+	}
+}
+
+// CarAvailabilityResult is one car in the availability search response
+type CarAvailabilityResult struct {
+	Car            models.Car   `json:"car"`
+	FleetSize      int          `json:"fleet_size"`
+	AvailableUnits int          `json:"available_units"`
+	RentalDays     int          `json:"rental_days"`
+	QuotedPrice    money.Amount `json:"quoted_price"`
+	// Bookable is set when units are free and the window fits the car's rental length limits;
+	// RentalLengthError says why it does not fit
+	Bookable          bool   `json:"bookable"`
+	RentalLengthError string `json:"rental_length_error,omitempty"`
+
+	// Converted is only filled when the search is requested with ?currency=
+	Converted *models.CurrencyConversion `json:"converted,omitempty"`
+}
+
+// GetAvailableCars lists every car with the units that stay free for the whole window and
+// whether the window can be booked at all
+func GetAvailableCars(c *gin.Context) {
+	start, err := utils.ParseDateParam(c.Query("start"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid start: " + err.Error()})
+		return
+	}
+
+	end, err := utils.ParseDateParam(c.Query("end"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid end: " + err.Error()})
+		return
+	}
+
+	if start.After(end) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Start date must be before end date"})
+		return
+	}
+
+	rate, ok := requestedExchangeRate(c)
+	if !ok {
+		return
+	}
+
+	var cars []models.Car
+	if err := database.DB.Where("deleted_at IS NULL").Order("no").Find(&cars).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve cars"})
+		return
+	}
+
+	reserved, err := utils.PeakReservedUnitsByCar(database.DB, start, end)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate car availability"})
+		return
+	}
+
+	rules, err := pricing.LoadRateRules(database.DB, 0, start, end)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load rate rules"})
+		return
+	}
+
+	taxRates, err := pricing.LoadTaxRates(database.DB)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load tax rates"})
+		return
+	}
+
+	results := make([]CarAvailabilityResult, 0, len(cars))
+	for i := range cars {
+		availability := utils.NewCarAvailability(&cars[i], reserved[cars[i].No])
+		quote := pricing.Calculate(pricing.Input{Car: cars[i], StartRent: start, EndRent: end, RateRules: rules, TaxRates: taxRates})
+		result := CarAvailabilityResult{
+			Car:            cars[i],
+			FleetSize:      availability.FleetSize,
+			AvailableUnits: availability.Available,
+			RentalDays:     quote.RentalDays,
+			QuotedPrice:    quote.GrandTotal,
+		}
+		result.RentalLengthError = utils.CheckRentalLength(&cars[i], quote.RentalDays)
+		result.Bookable = result.AvailableUnits > 0 && result.RentalLengthError == ""
+		if rate != nil {
+			result.Car.Converted = rate.Convert(cars[i].PriceAmounts())
+			result.Converted = rate.Convert(map[string]money.Amount{"quoted_price": quote.GrandTotal})
+		}
+		results = append(results, result)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":  results,
+		"start": start,
+		"end":   end,
+	})
 }
 
 func CreateCar(c *gin.Context) {
@@ -61,12 +195,18 @@ func CreateCar(c *gin.Context) {
 		return
 	}
 
+	if message := utils.CheckRentalLimits(car.MinRentalDays, car.MaxRentalDays); message != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": message})
+		return
+	}
+
 	result := database.DB.Create(&car)
 	if result.Error != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create car"})
 		return
 	}
 
+	utils.SetETag(c, car.Version)
 	c.JSON(http.StatusCreated, gin.H{"data": car})
 }
 
@@ -81,19 +221,71 @@ func UpdateCar(c *gin.Context) {
 		return
 	}
 
-	var updateData models.Car
+	if !utils.IfMatch(c, car.Version) {
+		utils.RespondWithVersionConflict(c, "car", car.No, car.Version)
+		return
+	}
+
+	var updateData models.CarUpdate
 	if err := c.ShouldBindJSON(&updateData); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Update only provided fields
-	result := database.DB.Model(car).Updates(updateData)
-	if result.Error != nil {
+	updates := map[string]interface{}{
+		"name":       updateData.Name,
+		"stock":      updateData.Stock,
+		"daily_rent": updateData.DailyRent,
+	}
+
+	// Optional fields left out of the request keep their stored value, unless they are cleared
+	optional := []struct {
+		column string
+		set    bool
+		value  interface{}
+	}{
+		{"weekend_rent", updateData.WeekendRent != nil, updateData.WeekendRent},
+		{"min_rental_days", updateData.MinRentalDays != nil, updateData.MinRentalDays},
+		{"max_rental_days", updateData.MaxRentalDays != nil, updateData.MaxRentalDays},
+	}
+	for _, field := range optional {
+		switch {
+		case field.set && updateData.Clears(field.column):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot both set and clear " + field.column})
+			return
+		case field.set:
+			updates[field.column] = field.value
+		case updateData.Clears(field.column):
+			updates[field.column] = nil
+		}
+	}
+
+	minDays, maxDays := car.MinRentalDays, car.MaxRentalDays
+	if updateData.MinRentalDays != nil || updateData.Clears("min_rental_days") {
+		minDays = updateData.MinRentalDays
+	}
+	if updateData.MaxRentalDays != nil || updateData.Clears("max_rental_days") {
+		maxDays = updateData.MaxRentalDays
+	}
+	if message := utils.CheckRentalLimits(minDays, maxDays); message != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": message})
+		return
+	}
+
+	// Update only provided and cleared fields
+	updated, err := utils.UpdateVersioned(database.DB, car, car.No, car.Version, updates)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update car"})
 		return
 	}
 
+	database.DB.First(car, car.No)
+	if !updated {
+		utils.RespondWithVersionConflict(c, "car", car.No, car.Version)
+		return
+	}
+
+	utils.SetETag(c, car.Version)
 	c.JSON(http.StatusOK, gin.H{"data": car})
 }
 
@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"car-rental/pkg/database"
+	"car-rental/pkg/models"
+	"car-rental/pkg/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Helper function to find a unit by ID under the car given in the URL
+func findCarUnitByID(c *gin.Context, car *models.Car) (*models.CarUnit, int, error) {
+	id := c.Param("unit_id")
+	unitID, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	var unit models.CarUnit
+	result := database.DB.Where("car_id = ? AND deleted_at IS NULL", car.No).First(&unit, unitID)
+	if result.Error != nil {
+		return nil, http.StatusNotFound, result.Error
+	}
+
+	return &unit, http.StatusOK, nil
+}
+
+// Helper function to resolve both the car and the unit from the URL, writing the error response
+func findCarAndUnit(c *gin.Context) (*models.Car, *models.CarUnit, bool) {
+	car, status, err := findCarByID(c)
+	if err != nil {
+		if status == http.StatusBadRequest {
+			c.JSON(status, gin.H{"error": "Invalid car ID"})
+		} else {
+			c.JSON(status, gin.H{"error": "Car not found"})
+		}
+		return nil, nil, false
+	}
+
+	unit, status, err := findCarUnitByID(c, car)
+	if err != nil {
+		if status == http.StatusBadRequest {
+			c.JSON(status, gin.H{"error": "Invalid car unit ID"})
+		} else {
+			c.JSON(status, gin.H{"error": "Car unit not found"})
+		}
+		return nil, nil, false
+	}
+
+	return car, unit, true
+}
+
+func GetCarUnits(c *gin.Context) {
+	car, status, err := findCarByID(c)
+	if err != nil {
+		if status == http.StatusBadRequest {
+			c.JSON(status, gin.H{"error": "Invalid car ID"})
+		} else {
+			c.JSON(status, gin.H{"error": "Car not found"})
+		}
+		return
+	}
+
+	var units []models.CarUnit
+	result := database.DB.Where("car_id = ? AND deleted_at IS NULL", car.No).Order("no").Find(&units)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve car units"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": units})
+}
+
+func GetCarUnit(c *gin.Context) {
+	_, unit, ok := findCarAndUnit(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": unit})
+}
+
+func CreateCarUnit(c *gin.Context) {
+	car, status, err := findCarByID(c)
+	if err != nil {
+		if status == http.StatusBadRequest {
+			c.JSON(status, gin.H{"error": "Invalid car ID"})
+		} else {
+			c.JSON(status, gin.H{"error": "Car not found"})
+		}
+		return
+	}
+
+	var unit models.CarUnit
+	if err := c.ShouldBindJSON(&unit); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	unit.CarID = car.No
+	if unit.Status == "" {
+		unit.Status = models.CAR_UNIT_STATUS_AVAILABLE
+	}
+
+	result := database.DB.Create(&unit)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create car unit"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"data": unit})
+}
+
+func UpdateCarUnit(c *gin.Context) {
+	_, unit, ok := findCarAndUnit(c)
+	if !ok {
+		return
+	}
+
+	var updateData models.CarUnitUpdate
+	if err := c.ShouldBindJSON(&updateData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Update only provided fields
+	result := database.DB.Model(unit).Updates(updateData)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update car unit"})
+		return
+	}
+
+	database.DB.First(unit, unit.No)
+
+	c.JSON(http.StatusOK, gin.H{"data": unit})
+}
+
+func DeleteCarUnit(c *gin.Context) {
+	_, unit, ok := findCarAndUnit(c)
+	if !ok {
+		return
+	}
+
+	constraints := utils.CheckCarUnitBookingConstraints(unit.No)
+
+	if constraints.HasActive {
+		details := map[string]interface{}{
+			"active_bookings": constraints.ActiveBookings,
+			"total_bookings":  constraints.TotalBookings,
+		}
+		utils.RespondWithConstraintError(c, "car unit", unit.No, "active_bookings", details)
+		return
+	}
+
+	// Use soft delete so bookings keep pointing at the unit they used
+	err := utils.SoftDeleteCarUnit(unit.No)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to soft delete car unit"})
+		return
+	}
+
+	utils.RespondWithSoftDeleteSuccess(c, "car unit", unit.No)
+}
@@ -50,6 +50,7 @@ func GetCustomer(c *gin.Context) {
 		return
 	}
 
+	utils.SetETag(c, customer.Version)
 	c.JSON(http.StatusOK, gin.H{"data": customer})
 }
 
@@ -67,6 +68,7 @@ func CreateCustomer(c *gin.Context) {
 		return
 	}
 
+	utils.SetETag(c, customer.Version)
 	c.JSON(http.StatusCreated, gin.H{"data": customer})
 }
 
@@ -81,6 +83,11 @@ func UpdateCustomer(c *gin.Context) {
 		return
 	}
 
+	if !utils.IfMatch(c, customer.Version) {
+		utils.RespondWithVersionConflict(c, "customer", customer.No, customer.Version)
+		return
+	}
+
 	var updateData models.Customer
 	if err := c.ShouldBindJSON(&updateData); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -88,12 +95,19 @@ func UpdateCustomer(c *gin.Context) {
 	}
 
 	// Update only provided fields
-	result := database.DB.Model(customer).Updates(updateData)
-	if result.Error != nil {
+	updated, err := utils.UpdateVersioned(database.DB, customer, customer.No, customer.Version, updateData)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update customer"})
 		return
 	}
 
+	database.DB.First(customer, customer.No)
+	if !updated {
+		utils.RespondWithVersionConflict(c, "customer", customer.No, customer.Version)
+		return
+	}
+
+	utils.SetETag(c, customer.Version)
 	c.JSON(http.StatusOK, gin.H{"data": customer})
 }
 
@@ -141,6 +155,11 @@ func SubscribeToMembership(c *gin.Context) {
 		return
 	}
 
+	subscribeToMembership(c, customer)
+}
+
+// subscribeToMembership subscribes a loaded customer to the membership in the URL
+func subscribeToMembership(c *gin.Context, customer *models.Customer) {
 	membershipIDStr := c.Param("membership_id")
 	membershipID, err := strconv.Atoi(membershipIDStr)
 	if err != nil {
@@ -183,6 +202,11 @@ func UnsubscribeFromMembership(c *gin.Context) {
 		return
 	}
 
+	unsubscribeFromMembership(c, customer)
+}
+
+// unsubscribeFromMembership removes the membership of a loaded customer
+func unsubscribeFromMembership(c *gin.Context, customer *models.Customer) {
 	if customer.MembershipID == nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Customer is not subscribed to any membership"})
 		return
@@ -1,8 +1,10 @@
 package handlers
 
 import (
+	"car-rental/pkg/auth"
 	"car-rental/pkg/database"
 	"car-rental/pkg/models"
+	"car-rental/pkg/money"
 	"car-rental/pkg/utils"
 	"net/http"
 	"strconv"
@@ -39,6 +41,12 @@ func findDriverByID(c *gin.Context) (*models.Driver, int, error) {
 	return &driver, http.StatusOK, nil
 }
 
+// isOwnDriver reports whether a driver is the one linked to the signed-in user
+func isOwnDriver(c *gin.Context, driver *models.Driver) bool {
+	claims := auth.CurrentClaims(c)
+	return claims.Role == models.ROLE_DRIVER && claims.DriverID != nil && *claims.DriverID == driver.No
+}
+
 func GetDriver(c *gin.Context) {
 	driver, status, err := findDriverByID(c)
 	if err != nil {
@@ -50,6 +58,12 @@ func GetDriver(c *gin.Context) {
 		return
 	}
 
+	if auth.OwnRecordsOnly(c) && !isOwnDriver(c, driver) {
+		auth.RespondNotOwnRecord(c, "driver", driver.No)
+		return
+	}
+
+	utils.SetETag(c, driver.Version)
 	c.JSON(http.StatusOK, gin.H{"data": driver})
 }
 
@@ -67,6 +81,7 @@ func CreateDriver(c *gin.Context) {
 		return
 	}
 
+	utils.SetETag(c, driver.Version)
 	c.JSON(http.StatusCreated, gin.H{"data": driver})
 }
 
@@ -81,18 +96,30 @@ func UpdateDriver(c *gin.Context) {
 		return
 	}
 
+	if !utils.IfMatch(c, driver.Version) {
+		utils.RespondWithVersionConflict(c, "driver", driver.No, driver.Version)
+		return
+	}
+
 	var UpdateDriver models.Driver
 	if err := c.ShouldBindJSON(&UpdateDriver); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	result := database.DB.Model(driver).Updates(UpdateDriver)
-	if result.Error != nil {
+	updated, err := utils.UpdateVersioned(database.DB, driver, driver.No, driver.Version, UpdateDriver)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update driver"})
 		return
 	}
 
+	database.DB.First(driver, driver.No)
+	if !updated {
+		utils.RespondWithVersionConflict(c, "driver", driver.No, driver.Version)
+		return
+	}
+
+	utils.SetETag(c, driver.Version)
 	c.JSON(http.StatusOK, gin.H{"data": driver})
 }
 
@@ -139,6 +166,11 @@ func GetDriverIncentives(c *gin.Context) {
 		return
 	}
 
+	if auth.OwnRecordsOnly(c) && !isOwnDriver(c, driver) {
+		auth.RespondNotOwnRecord(c, "driver", driver.No)
+		return
+	}
+
 	var incentives []models.DriverIncentive
 	result := database.DB.Preload("Booking").Preload("Booking.Customer").Preload("Booking.Car").
 		Joins("JOIN bookings ON driver_incentives.booking_id = bookings.no").
@@ -151,7 +183,7 @@ func GetDriverIncentives(c *gin.Context) {
 	}
 
 	// Calculate total incentives
-	var totalIncentives float64
+	var totalIncentives money.Amount
 	for _, incentive := range incentives {
 		totalIncentives += incentive.Incentive
 	}
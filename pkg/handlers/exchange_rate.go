@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"car-rental/pkg/database"
+	"car-rental/pkg/models"
+	"car-rental/pkg/utils"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetExchangeRates lists the rate in effect for each currency, or every rate of one
+// currency with ?currency=
+func GetExchangeRates(c *gin.Context) {
+	var rates []models.ExchangeRate
+
+	if currency := strings.ToUpper(c.Query("currency")); currency != "" {
+		result := database.DB.Where("currency = ?", currency).Order("effective_from DESC, no DESC").Find(&rates)
+		if result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve exchange rates"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"data": rates})
+		return
+	}
+
+	result := database.DB.Raw(`SELECT DISTINCT ON (currency) * FROM exchange_rates
+		WHERE effective_from <= ?
+		ORDER BY currency, effective_from DESC, no DESC`, time.Now()).Scan(&rates)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve exchange rates"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": rates})
+}
+
+// Helper function to find an exchange rate by ID
+func findExchangeRateByID(c *gin.Context) (*models.ExchangeRate, int, error) {
+	id := c.Param("id")
+	rateID, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	var rate models.ExchangeRate
+	result := database.DB.First(&rate, rateID)
+	if result.Error != nil {
+		return nil, http.StatusNotFound, result.Error
+	}
+
+	return &rate, http.StatusOK, nil
+}
+
+func GetExchangeRate(c *gin.Context) {
+	rate, status, err := findExchangeRateByID(c)
+	if err != nil {
+		if status == http.StatusBadRequest {
+			c.JSON(status, gin.H{"error": "Invalid exchange rate ID"})
+		} else {
+			c.JSON(status, gin.H{"error": "Exchange rate not found"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": rate})
+}
+
+// CreateExchangeRate adds a new rate for a currency. Existing rates are kept for the
+// bookings that recorded them, so a rate is changed by adding a newer one.
+func CreateExchangeRate(c *gin.Context) {
+	var rate models.ExchangeRate
+
+	if err := c.ShouldBindJSON(&rate); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rate.No = 0
+	rate.Currency = strings.ToUpper(rate.Currency)
+	if rate.Currency == models.BASE_CURRENCY {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Prices are stored in " + models.BASE_CURRENCY + ", it needs no exchange rate"})
+		return
+	}
+	if rate.EffectiveFrom.IsZero() {
+		rate.EffectiveFrom = time.Now()
+	}
+
+	result := database.DB.Create(&rate)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create exchange rate"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"data": rate})
+}
+
+// requestedExchangeRate reads the ?currency= option of a response. It returns nil when
+// the base currency is asked for, and responds with an error when no rate is known.
+func requestedExchangeRate(c *gin.Context) (*models.ExchangeRate, bool) {
+	currency := strings.ToUpper(c.Query("currency"))
+	if currency == "" || currency == models.BASE_CURRENCY {
+		return nil, true
+	}
+
+	rate, err := utils.FindExchangeRate(database.DB, currency, time.Now())
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No exchange rate for currency " + currency})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load exchange rate"})
+		return nil, false
+	}
+	return rate, true
+}
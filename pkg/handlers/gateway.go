@@ -0,0 +1,417 @@
+package handlers
+
+import (
+	"car-rental/pkg/database"
+	"car-rental/pkg/gateway"
+	"car-rental/pkg/models"
+	"car-rental/pkg/money"
+	"car-rental/pkg/utils"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Provider used when a charge request does not name one
+const DEFAULT_PAYMENT_PROVIDER = gateway.FAKE_PROVIDER_NAME
+
+// GetBookingCharges lists the online charges started for a booking
+func GetBookingCharges(c *gin.Context) {
+	booking, status, err := findBookingByID(c)
+	if err != nil {
+		if status == http.StatusBadRequest {
+			c.JSON(status, gin.H{"error": "Invalid booking ID"})
+		} else {
+			c.JSON(status, gin.H{"error": "Booking not found"})
+		}
+		return
+	}
+
+	var charges []models.GatewayCharge
+	if err := database.DB.Where("booking_id = ?", booking.No).Order("no").Find(&charges).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve charges"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": charges})
+}
+
+// CreateBookingCharge starts an online payment. The customer completes it on the provider's
+// checkout page and the ledger entry is written when the provider's webhook arrives.
+func CreateBookingCharge(c *gin.Context) {
+	booking, status, err := findBookingByID(c)
+	if err != nil {
+		if status == http.StatusBadRequest {
+			c.JSON(status, gin.H{"error": "Invalid booking ID"})
+		} else {
+			c.JSON(status, gin.H{"error": "Booking not found"})
+		}
+		return
+	}
+
+	var request models.GatewayChargeRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	providerName := request.Provider
+	if providerName == "" {
+		providerName = utils.GetEnv("PAYMENT_PROVIDER", DEFAULT_PAYMENT_PROVIDER)
+	}
+	provider, ok := gateway.Get(providerName)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown payment provider '" + providerName + "'"})
+		return
+	}
+
+	// The same rules as a manual payment apply to the amount asked for
+	balance, err := utils.CalculateBookingBalance(database.DB, booking)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate balance"})
+		return
+	}
+	payment := models.Payment{Type: request.Type, Amount: request.Amount}
+	if message := checkPayment(booking, balance, &payment); message != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": message})
+		return
+	}
+
+	charge, err := provider.CreateCharge(c.Request.Context(), gateway.ChargeRequest{
+		BookingID:   booking.No,
+		Amount:      payment.Amount,
+		Description: fmt.Sprintf("Booking %d %s", booking.No, request.Type),
+	})
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Payment provider rejected the charge: " + err.Error()})
+		return
+	}
+
+	record := models.GatewayCharge{
+		BookingID:   booking.No,
+		Provider:    provider.Name(),
+		ChargeID:    charge.ID,
+		PaymentType: request.Type,
+		Amount:      charge.Amount,
+		Status:      charge.Status,
+		CheckoutURL: charge.CheckoutURL,
+	}
+	if err := database.DB.Create(&record).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record charge"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"data": record})
+}
+
+// RefundBookingCharge sends money back through the provider a charge was paid with
+func RefundBookingCharge(c *gin.Context) {
+	booking, status, err := findBookingByID(c)
+	if err != nil {
+		if status == http.StatusBadRequest {
+			c.JSON(status, gin.H{"error": "Invalid booking ID"})
+		} else {
+			c.JSON(status, gin.H{"error": "Booking not found"})
+		}
+		return
+	}
+
+	var request models.GatewayRefundRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	amount := request.Amount
+
+	// The charge and booking are locked while the refund is checked and recorded as pending.
+	// Pending refunds count against what is left, so two refunds of the same charge cannot
+	// both pass against the same remaining amount. The provider is only called after the
+	// commit, so no lock is held while waiting on it.
+	var charge models.GatewayCharge
+	refund := models.GatewayRefund{BookingID: booking.No, Amount: amount, Reason: request.Reason, Status: gateway.CHARGE_STATUS_PENDING}
+	payment := models.Payment{BookingID: booking.No, Type: models.PAYMENT_TYPE_REFUND, Amount: amount, Note: request.Reason}
+	rejectionStatus, rejection := http.StatusBadRequest, ""
+	reject := func(status int, message string) error {
+		rejectionStatus, rejection = status, message
+		return errPaymentRejected
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("booking_id = ? AND charge_id = ?", booking.No, c.Param("charge_id")).
+			First(&charge)
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return reject(http.StatusNotFound, "Charge not found")
+		}
+		if result.Error != nil {
+			return result.Error
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(booking, booking.No).Error; err != nil {
+			return err
+		}
+
+		if charge.Status != gateway.CHARGE_STATUS_SUCCEEDED && charge.Status != gateway.CHARGE_STATUS_REFUNDED {
+			return reject(http.StatusBadRequest, "Only succeeded charges can be refunded")
+		}
+		onCharge, err := pendingRefunds(tx, "charge_no = ?", charge.No)
+		if err != nil {
+			return err
+		}
+		if refundable := charge.Amount - charge.RefundedAmount - onCharge; amount > refundable {
+			return reject(http.StatusBadRequest, fmt.Sprintf("Refund of %s exceeds the %s left on the charge", amount, refundable))
+		}
+
+		current, err := utils.CalculateBookingBalance(tx, booking)
+		if err != nil {
+			return err
+		}
+		onBooking, err := pendingRefunds(tx, "booking_id = ?", booking.No)
+		if err != nil {
+			return err
+		}
+		current.NetPaid -= onBooking
+		if message := checkRefund(booking, current, &payment); message != "" {
+			return reject(http.StatusBadRequest, message)
+		}
+
+		if _, ok := gateway.Get(charge.Provider); !ok {
+			return reject(http.StatusInternalServerError, "Payment provider '"+charge.Provider+"' is not configured")
+		}
+
+		refund.ChargeNo = charge.No
+		return tx.Create(&refund).Error
+	})
+	if errors.Is(err, errPaymentRejected) {
+		c.JSON(rejectionStatus, gin.H{"error": rejection})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refund charge"})
+		return
+	}
+
+	provider, _ := gateway.Get(charge.Provider)
+	sent, err := provider.Refund(c.Request.Context(), charge.ChargeID, amount)
+	if err != nil {
+		refund.Status = gateway.CHARGE_STATUS_FAILED
+		if err := database.DB.Model(&refund).Update("status", refund.Status).Error; err != nil {
+			log.Printf("Refund %d for charge %s was rejected but could not be marked failed: %v", refund.No, charge.ChargeID, err)
+		}
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Payment provider rejected the refund: " + err.Error()})
+		return
+	}
+
+	// The money has left the provider, so the ledger records it even if it overshoots the balance
+	var balance *utils.BookingBalance
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&charge, charge.No).Error; err != nil {
+			return err
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(booking, booking.No).Error; err != nil {
+			return err
+		}
+
+		payment.Method = "gateway:" + charge.Provider
+		payment.Reference = sent.ID
+		payment.PaidAt = time.Now()
+		if err := tx.Create(&payment).Error; err != nil {
+			return err
+		}
+
+		err := tx.Model(&charge).Updates(map[string]interface{}{
+			"refunded_amount": gorm.Expr("refunded_amount + ?", amount),
+			"status":          gateway.CHARGE_STATUS_REFUNDED,
+		}).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.First(&charge, charge.No).Error; err != nil {
+			return err
+		}
+
+		refund.Status = gateway.CHARGE_STATUS_SUCCEEDED
+		refund.RefundID = sent.ID
+		refund.PaymentID = &payment.No
+		err = tx.Model(&refund).Updates(map[string]interface{}{
+			"status":     refund.Status,
+			"refund_id":  refund.RefundID,
+			"payment_id": payment.No,
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		balance, err = utils.RefreshPaymentStatus(tx, booking)
+		return err
+	})
+	if err != nil {
+		// The refund stays pending, so its amount remains reserved until it is reconciled
+		log.Printf("Refund %s for charge %s was paid but could not be recorded, pending refund %d left open: %v", sent.ID, charge.ChargeID, refund.No, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Refund was sent but could not be recorded"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"data": payment, "charge": charge, "refund": refund, "balance": balance})
+}
+
+// pendingRefunds sums the gateway refunds matching a condition that still wait on the provider
+func pendingRefunds(tx *gorm.DB, query string, arg interface{}) (money.Amount, error) {
+	var pending struct {
+		Total money.Amount
+	}
+	err := tx.Model(&models.GatewayRefund{}).
+		Select("COALESCE(SUM(amount), 0) AS total").
+		Where(query, arg).
+		Where("status = ?", gateway.CHARGE_STATUS_PENDING).
+		Scan(&pending).Error
+	return pending.Total, err
+}
+
+// HandlePaymentWebhook receives a provider's signed callback and applies it to the booking
+func HandlePaymentWebhook(c *gin.Context) {
+	provider, ok := gateway.Get(c.Param("provider"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown payment provider"})
+		return
+	}
+
+	payload, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read webhook body"})
+		return
+	}
+
+	status, response := processWebhook(provider, payload, c.Request.Header)
+	c.JSON(status, response)
+}
+
+// CompleteFakeCharge plays the customer on the fake provider's checkout page and delivers the
+// resulting signed webhook, so the online flow can be exercised without network access
+func CompleteFakeCharge(c *gin.Context) {
+	provider, ok := gateway.Get(gateway.FAKE_PROVIDER_NAME)
+	fake, isFake := provider.(*gateway.FakeProvider)
+	if !ok || !isFake {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Fake payment provider is not enabled"})
+		return
+	}
+
+	var completion models.FakeChargeCompletion
+	if err := c.ShouldBindJSON(&completion); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	payload, header, err := fake.Complete(c.Param("charge_id"), completion.Outcome == gateway.CHARGE_STATUS_SUCCEEDED)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	status, response := processWebhook(provider, payload, header)
+	c.JSON(status, response)
+}
+
+// errChargeNotFound is returned for events about charges this system never started
+var errChargeNotFound = errors.New("charge not found")
+
+// processWebhook verifies a webhook and applies its event. Events are idempotent: a charge
+// that already left pending is acknowledged without changing anything.
+func processWebhook(provider gateway.Provider, payload []byte, header http.Header) (int, gin.H) {
+	event, err := provider.ParseWebhook(payload, header)
+	if errors.Is(err, gateway.ErrInvalidSignature) {
+		return http.StatusUnauthorized, gin.H{"error": "Invalid webhook signature"}
+	}
+	if err != nil {
+		return http.StatusBadRequest, gin.H{"error": "Invalid webhook payload"}
+	}
+
+	var charge models.GatewayCharge
+	var booking models.Booking
+	processed := false
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("provider = ? AND charge_id = ?", provider.Name(), event.ChargeID).
+			First(&charge)
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return errChargeNotFound
+		}
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if charge.Status != gateway.CHARGE_STATUS_PENDING {
+			return nil
+		}
+		processed = true
+
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&booking, charge.BookingID).Error; err != nil {
+			return err
+		}
+
+		switch event.Type {
+		case gateway.EVENT_CHARGE_SUCCEEDED:
+			return applyChargeSucceeded(tx, &charge, &booking, event)
+		case gateway.EVENT_CHARGE_FAILED:
+			charge.Status = gateway.CHARGE_STATUS_FAILED
+			return tx.Model(&charge).Update("status", charge.Status).Error
+		}
+		processed = false
+		return nil
+	})
+	if errors.Is(err, errChargeNotFound) {
+		return http.StatusNotFound, gin.H{"error": "Charge not found"}
+	}
+	if err != nil {
+		return http.StatusInternalServerError, gin.H{"error": "Failed to process webhook"}
+	}
+
+	if !processed {
+		return http.StatusOK, gin.H{"message": "Event already processed or ignored", "event": event, "charge": charge}
+	}
+
+	bookingWithRelations(database.DB).First(&booking, booking.No)
+	return http.StatusOK, gin.H{"message": "Event processed", "event": event, "charge": charge, "booking": booking}
+}
+
+// applyChargeSucceeded writes the ledger entry for a paid charge. The money has already been
+// taken, so it is recorded even when it overshoots the balance; a deposit also confirms a
+// reservation that is still pending.
+func applyChargeSucceeded(tx *gorm.DB, charge *models.GatewayCharge, booking *models.Booking, event *gateway.Event) error {
+	paidAt := event.OccurredAt
+	if paidAt.IsZero() {
+		paidAt = time.Now()
+	}
+
+	payment := models.Payment{
+		BookingID: booking.No,
+		Type:      charge.PaymentType,
+		Amount:    charge.Amount,
+		Method:    "gateway:" + charge.Provider,
+		Reference: charge.ChargeID,
+		PaidAt:    paidAt,
+	}
+	if err := tx.Create(&payment).Error; err != nil {
+		return err
+	}
+
+	charge.Status = gateway.CHARGE_STATUS_SUCCEEDED
+	charge.PaymentID = &payment.No
+	if err := tx.Model(charge).Updates(map[string]interface{}{"status": charge.Status, "payment_id": payment.No}).Error; err != nil {
+		return err
+	}
+
+	if charge.PaymentType == models.PAYMENT_TYPE_DEPOSIT && booking.Status == models.BOOKING_STATUS_PENDING {
+		if err := setBookingStatus(tx, booking, models.BOOKING_STATUS_CONFIRMED); err != nil {
+			return err
+		}
+	}
+
+	_, err := utils.RefreshPaymentStatus(tx, booking)
+	return err
+}
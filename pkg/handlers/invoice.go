@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"bytes"
+	"car-rental/pkg/auth"
+	"car-rental/pkg/database"
+	"car-rental/pkg/invoice"
+	"car-rental/pkg/models"
+	"car-rental/pkg/utils"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GetBookingInvoice serves the invoice of a returned booking as JSON, HTML (?format=html)
+// or PDF (?format=pdf)
+func GetBookingInvoice(c *gin.Context) {
+	format := strings.ToLower(c.DefaultQuery("format", models.INVOICE_FORMAT_JSON))
+	if format != models.INVOICE_FORMAT_JSON && format != models.INVOICE_FORMAT_HTML && format != models.INVOICE_FORMAT_PDF {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid format, use json, html or pdf"})
+		return
+	}
+
+	booking, status, err := findBookingByID(c)
+	if err != nil {
+		if status == http.StatusBadRequest {
+			c.JSON(status, gin.H{"error": "Invalid booking ID"})
+		} else {
+			c.JSON(status, gin.H{"error": "Booking not found"})
+		}
+		return
+	}
+
+	if auth.OwnRecordsOnly(c) && !isOwnBooking(c, booking) {
+		auth.RespondNotOwnRecord(c, "booking", booking.No)
+		return
+	}
+
+	inv, status, err := findOrIssueInvoice(booking)
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	switch format {
+	case models.INVOICE_FORMAT_HTML:
+		var page bytes.Buffer
+		if err := invoice.RenderHTML(&page, inv); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render invoice"})
+			return
+		}
+		c.Data(http.StatusOK, "text/html; charset=utf-8", page.Bytes())
+	case models.INVOICE_FORMAT_PDF:
+		c.Header("Content-Disposition", fmt.Sprintf("inline; filename=%q", inv.Number+".pdf"))
+		c.Data(http.StatusOK, "application/pdf", invoice.RenderPDF(inv))
+	default:
+		c.JSON(http.StatusOK, gin.H{"data": inv})
+	}
+}
+
+// findOrIssueInvoice loads the invoice of a booking. Bookings returned before invoices
+// existed get theirs issued on first request.
+func findOrIssueInvoice(booking *models.Booking) (*models.Invoice, int, error) {
+	if !booking.Finished {
+		return nil, http.StatusConflict, errors.New("Invoices are issued once the booking is returned")
+	}
+
+	inv, err := findInvoice(database.DB, booking.No)
+	if err == nil {
+		return inv, http.StatusOK, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, http.StatusInternalServerError, errors.New("Failed to retrieve invoice")
+	}
+
+	// Concurrent first requests queue on the booking row, so only the first one issues the
+	// invoice and takes a number; the others find it once they get the lock
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&models.Booking{}, booking.No).Error; err != nil {
+			return err
+		}
+
+		inv, err = findInvoice(tx, booking.No)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			inv, err = utils.IssueInvoice(tx, booking, time.Now())
+		}
+		return err
+	})
+	if err != nil {
+		return nil, http.StatusInternalServerError, errors.New("Failed to issue invoice")
+	}
+	return inv, http.StatusOK, nil
+}
+
+// findInvoice loads the invoice of a booking with its lines in order
+func findInvoice(db *gorm.DB, bookingID int) (*models.Invoice, error) {
+	var inv models.Invoice
+	err := db.Preload("Lines", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position")
+	}).Where("booking_id = ?", bookingID).First(&inv).Error
+	if err != nil {
+		return nil, err
+	}
+	return &inv, nil
+}
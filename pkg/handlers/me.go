@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"car-rental/pkg/auth"
+	"car-rental/pkg/database"
+	"car-rental/pkg/models"
+	"car-rental/pkg/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// The /me routes act for the customer linked to the signed-in user. They run the same
+// checks as the staff routes, with the customer taken from the access token.
+
+// findMyCustomer loads the customer of the signed-in user, responding with an error when
+// there is none
+func findMyCustomer(c *gin.Context) (*models.Customer, bool) {
+	claims := auth.CurrentClaims(c)
+	if claims == nil || claims.CustomerID == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Customer not found"})
+		return nil, false
+	}
+
+	var customer models.Customer
+	if err := database.DB.Where("deleted_at IS NULL").Preload("Membership").First(&customer, *claims.CustomerID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Customer not found"})
+		return nil, false
+	}
+
+	return &customer, true
+}
+
+// findMyBooking loads the booking in the URL if it belongs to the signed-in customer
+func findMyBooking(c *gin.Context) (*models.Booking, bool) {
+	booking, status, err := findBookingByID(c)
+	if err != nil {
+		if status == http.StatusBadRequest {
+			c.JSON(status, gin.H{"error": "Invalid booking ID"})
+		} else {
+			c.JSON(status, gin.H{"error": "Booking not found"})
+		}
+		return nil, false
+	}
+
+	if !isOwnBooking(c, booking) {
+		auth.RespondNotOwnRecord(c, "booking", booking.No)
+		return nil, false
+	}
+
+	return booking, true
+}
+
+// myBookingRequest turns a self-service booking body into a request for the signed-in customer
+func myBookingRequest(customer *models.Customer, request models.MyBookingRequest) models.BookingRequest {
+	return models.BookingRequest{
+		CustomerID:    customer.No,
+		CarsID:        request.CarsID,
+		StartRent:     request.StartRent,
+		EndRent:       request.EndRent,
+		BookingTypeID: request.BookingTypeID,
+		DriverID:      request.DriverID,
+		PromoCode:     request.PromoCode,
+	}
+}
+
+// GetMe returns the signed-in user and their customer record
+func GetMe(c *gin.Context) {
+	customer, ok := findMyCustomer(c)
+	if !ok {
+		return
+	}
+
+	userID, err := auth.CurrentClaims(c).UserID()
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": gin.H{"user": user, "customer": customer}})
+}
+
+func QuoteMyBooking(c *gin.Context) {
+	var request models.MyBookingRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	customer, ok := findMyCustomer(c)
+	if !ok {
+		return
+	}
+
+	quoteBooking(c, myBookingRequest(customer, request))
+}
+
+func CreateMyBooking(c *gin.Context) {
+	var request models.MyBookingRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	customer, ok := findMyCustomer(c)
+	if !ok {
+		return
+	}
+
+	createBooking(c, myBookingRequest(customer, request))
+}
+
+// UpdateMyBooking moves the dates of one of the customer's bookings and reprices it
+func UpdateMyBooking(c *gin.Context) {
+	booking, ok := findMyBooking(c)
+	if !ok {
+		return
+	}
+
+	var request models.MyBookingUpdate
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updateBooking(c, booking, models.BookingUpdate{
+		StartRent: request.StartRent,
+		EndRent:   request.EndRent,
+	})
+}
+
+// CancelMyBooking cancels one of the customer's reservations under the cancellation policy
+func CancelMyBooking(c *gin.Context) {
+	var cancellation models.BookingCancellation
+	if err := c.ShouldBindJSON(&cancellation); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	booking, ok := findMyBooking(c)
+	if !ok {
+		return
+	}
+
+	if !models.CanTransitionBooking(booking.Status, models.BOOKING_STATUS_CANCELLED) {
+		utils.RespondWithTransitionError(c, "booking", booking.No, booking.Status, models.BOOKING_STATUS_CANCELLED)
+		return
+	}
+
+	applyBookingTransition(c, booking, models.BOOKING_STATUS_CANCELLED, cancelWithReason(cancellation.Reason), "Booking cancelled successfully")
+}
+
+func SubscribeMeToMembership(c *gin.Context) {
+	customer, ok := findMyCustomer(c)
+	if !ok {
+		return
+	}
+
+	subscribeToMembership(c, customer)
+}
+
+func UnsubscribeMeFromMembership(c *gin.Context) {
+	customer, ok := findMyCustomer(c)
+	if !ok {
+		return
+	}
+
+	unsubscribeFromMembership(c, customer)
+}
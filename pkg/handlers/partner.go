@@ -0,0 +1,211 @@
+package handlers
+
+import (
+	"car-rental/pkg/auth"
+	"car-rental/pkg/database"
+	"car-rental/pkg/models"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func GetPartners(c *gin.Context) {
+	var partners []models.Partner
+	if err := database.DB.Preload("Customer").Order("no").Find(&partners).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve partners"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": partners})
+}
+
+// Helper function to find a partner by ID
+func findPartnerByID(c *gin.Context) (*models.Partner, int, error) {
+	id := c.Param("id")
+	partnerID, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	var partner models.Partner
+	result := database.DB.Preload("Customer").First(&partner, partnerID)
+	if result.Error != nil {
+		return nil, http.StatusNotFound, result.Error
+	}
+
+	return &partner, http.StatusOK, nil
+}
+
+// validatePartnerCustomer checks that the customer a partner books for exists
+func validatePartnerCustomer(customerID int) (int, string) {
+	var customer models.Customer
+	if err := database.DB.Where("deleted_at IS NULL").First(&customer, customerID).Error; err != nil {
+		return http.StatusBadRequest, "Customer not found or has been removed"
+	}
+	return http.StatusOK, ""
+}
+
+func GetPartner(c *gin.Context) {
+	partner, status, err := findPartnerByID(c)
+	if err != nil {
+		if status == http.StatusBadRequest {
+			c.JSON(status, gin.H{"error": "Invalid partner ID"})
+		} else {
+			c.JSON(status, gin.H{"error": "Partner not found"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": partner})
+}
+
+func CreatePartner(c *gin.Context) {
+	var partner models.Partner
+	if err := c.ShouldBindJSON(&partner); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if status, message := validatePartnerCustomer(partner.CustomerID); status != http.StatusOK {
+		c.JSON(status, gin.H{"error": message})
+		return
+	}
+
+	if err := database.DB.Create(&partner).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create partner"})
+		return
+	}
+
+	database.DB.Preload("Customer").First(&partner, partner.No)
+
+	c.JSON(http.StatusCreated, gin.H{"data": partner})
+}
+
+func UpdatePartner(c *gin.Context) {
+	partner, status, err := findPartnerByID(c)
+	if err != nil {
+		if status == http.StatusBadRequest {
+			c.JSON(status, gin.H{"error": "Invalid partner ID"})
+		} else {
+			c.JSON(status, gin.H{"error": "Partner not found"})
+		}
+		return
+	}
+
+	var updateData models.Partner
+	if err := c.ShouldBindJSON(&updateData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if status, message := validatePartnerCustomer(updateData.CustomerID); status != http.StatusOK {
+		c.JSON(status, gin.H{"error": message})
+		return
+	}
+
+	result := database.DB.Model(partner).Select("name", "contact_email", "customer_id").Updates(updateData)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update partner"})
+		return
+	}
+
+	database.DB.Preload("Customer").First(partner, partner.No)
+
+	c.JSON(http.StatusOK, gin.H{"data": partner})
+}
+
+// GetPartnerAPIKeys lists a partner's keys with their scopes and usage. Key hashes are never
+// returned.
+func GetPartnerAPIKeys(c *gin.Context) {
+	partner, status, err := findPartnerByID(c)
+	if err != nil {
+		if status == http.StatusBadRequest {
+			c.JSON(status, gin.H{"error": "Invalid partner ID"})
+		} else {
+			c.JSON(status, gin.H{"error": "Partner not found"})
+		}
+		return
+	}
+
+	var keys []models.APIKey
+	if err := database.DB.Where("partner_id = ?", partner.No).Order("no").Find(&keys).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve API keys"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": keys})
+}
+
+// CreatePartnerAPIKey issues a key. The response is the only time the key can be read.
+func CreatePartnerAPIKey(c *gin.Context) {
+	partner, status, err := findPartnerByID(c)
+	if err != nil {
+		if status == http.StatusBadRequest {
+			c.JSON(status, gin.H{"error": "Invalid partner ID"})
+		} else {
+			c.JSON(status, gin.H{"error": "Partner not found"})
+		}
+		return
+	}
+
+	var request models.APIKeyRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	key, apiKey, err := auth.NewAPIKey(partner.No, request)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate API key"})
+		return
+	}
+
+	if err := database.DB.Create(apiKey).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create API key"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Store this key now, it cannot be shown again",
+		"data":    models.IssuedAPIKey{APIKey: *apiKey, Key: key},
+	})
+}
+
+// RevokePartnerAPIKey stops a key from working. The row is kept with its usage.
+func RevokePartnerAPIKey(c *gin.Context) {
+	partner, status, err := findPartnerByID(c)
+	if err != nil {
+		if status == http.StatusBadRequest {
+			c.JSON(status, gin.H{"error": "Invalid partner ID"})
+		} else {
+			c.JSON(status, gin.H{"error": "Partner not found"})
+		}
+		return
+	}
+
+	keyID, err := strconv.Atoi(c.Param("key_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid API key ID"})
+		return
+	}
+
+	var apiKey models.APIKey
+	if err := database.DB.Where("partner_id = ?", partner.No).First(&apiKey, keyID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+		return
+	}
+
+	if apiKey.RevokedAt != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "API key is already revoked"})
+		return
+	}
+
+	if err := database.DB.Model(&apiKey).Update("revoked_at", time.Now()).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke API key"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "API key revoked successfully", "data": apiKey})
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"car-rental/pkg/auth"
+	"car-rental/pkg/database"
+	"car-rental/pkg/models"
+	"car-rental/pkg/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// The /partner routes act for the partner of the API key in the request. Bookings are made
+// for the partner's customer and run the same checks as the staff routes.
+
+// partnerBookingRequest turns a partner booking body into a request recorded against the partner
+func partnerBookingRequest(apiKey *models.APIKey, request models.MyBookingRequest) models.BookingRequest {
+	return models.BookingRequest{
+		CustomerID:    apiKey.Partner.CustomerID,
+		CarsID:        request.CarsID,
+		StartRent:     request.StartRent,
+		EndRent:       request.EndRent,
+		BookingTypeID: request.BookingTypeID,
+		DriverID:      request.DriverID,
+		PromoCode:     request.PromoCode,
+		PartnerID:     &apiKey.PartnerID,
+	}
+}
+
+func QuotePartnerBooking(c *gin.Context) {
+	var request models.MyBookingRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	quoteBooking(c, partnerBookingRequest(auth.CurrentAPIKey(c), request))
+}
+
+func CreatePartnerBooking(c *gin.Context) {
+	var request models.MyBookingRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	createBooking(c, partnerBookingRequest(auth.CurrentAPIKey(c), request))
+}
+
+// GetPartnerBookings lists the bookings created with any key of the partner
+func GetPartnerBookings(c *gin.Context) {
+	rate, ok := requestedExchangeRate(c)
+	if !ok {
+		return
+	}
+
+	var bookings []models.Booking
+	result := bookingWithRelations(database.DB).Where("partner_id = ?", auth.CurrentAPIKey(c).PartnerID).Order("no").Find(&bookings)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve bookings"})
+		return
+	}
+
+	if rate != nil {
+		for i := range bookings {
+			utils.ConvertBooking(&bookings[i], rate)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": bookings})
+}
+
+func GetPartnerBooking(c *gin.Context) {
+	booking, status, err := findBookingByID(c)
+	if err != nil {
+		if status == http.StatusBadRequest {
+			c.JSON(status, gin.H{"error": "Invalid booking ID"})
+		} else {
+			c.JSON(status, gin.H{"error": "Booking not found"})
+		}
+		return
+	}
+
+	if booking.PartnerID == nil || *booking.PartnerID != auth.CurrentAPIKey(c).PartnerID {
+		auth.RespondNotOwnRecord(c, "booking", booking.No)
+		return
+	}
+
+	rate, ok := requestedExchangeRate(c)
+	if !ok {
+		return
+	}
+	if rate != nil {
+		utils.ConvertBooking(booking, rate)
+	}
+
+	utils.SetETag(c, booking.Version)
+	c.JSON(http.StatusOK, gin.H{"data": booking})
+}
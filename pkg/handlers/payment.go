@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"car-rental/pkg/database"
+	"car-rental/pkg/models"
+	"car-rental/pkg/utils"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GetBookingPayments lists the payment ledger of a booking with its outstanding balance
+func GetBookingPayments(c *gin.Context) {
+	booking, status, err := findBookingByID(c)
+	if err != nil {
+		if status == http.StatusBadRequest {
+			c.JSON(status, gin.H{"error": "Invalid booking ID"})
+		} else {
+			c.JSON(status, gin.H{"error": "Booking not found"})
+		}
+		return
+	}
+
+	var payments []models.Payment
+	if err := database.DB.Where("booking_id = ?", booking.No).Order("paid_at, no").Find(&payments).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve payments"})
+		return
+	}
+
+	balance, err := utils.CalculateBookingBalance(database.DB, booking)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate balance"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": payments, "balance": balance})
+}
+
+// CreateBookingPayment records a deposit, installment or settlement against a booking
+func CreateBookingPayment(c *gin.Context) {
+	var request models.PaymentRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	paidAt, ok := paymentTime(c, request.PaidAt)
+	if !ok {
+		return
+	}
+
+	payment := models.Payment{
+		Type:      request.Type,
+		Amount:    request.Amount,
+		Method:    request.Method,
+		Reference: request.Reference,
+		Note:      request.Note,
+		PaidAt:    paidAt,
+	}
+	recordPayment(c, &payment, checkPayment)
+}
+
+// CreateBookingRefund pays money back to the customer, up to what they have paid
+func CreateBookingRefund(c *gin.Context) {
+	var request models.RefundRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	paidAt, ok := paymentTime(c, request.PaidAt)
+	if !ok {
+		return
+	}
+
+	payment := models.Payment{
+		Type:      models.PAYMENT_TYPE_REFUND,
+		Amount:    request.Amount,
+		Method:    request.Method,
+		Reference: request.Reference,
+		Note:      request.Reason,
+		PaidAt:    paidAt,
+	}
+	recordPayment(c, &payment, checkRefund)
+}
+
+// paymentTime reads the optional time the money moved, defaulting to now
+func paymentTime(c *gin.Context, paidAt *time.Time) (time.Time, bool) {
+	now := time.Now()
+	if paidAt == nil {
+		return now, true
+	}
+
+	if paidAt.After(now) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Payment time cannot be in the future"})
+		return time.Time{}, false
+	}
+
+	return *paidAt, true
+}
+
+// errPaymentRejected rolls back a payment that failed its check
+var errPaymentRejected = errors.New("payment rejected")
+
+// paymentCheck returns why a ledger entry cannot be recorded against the booking's balance,
+// or an empty string when it can
+type paymentCheck func(booking *models.Booking, balance *utils.BookingBalance, payment *models.Payment) string
+
+func checkPayment(booking *models.Booking, balance *utils.BookingBalance, payment *models.Payment) string {
+	if payment.Type == models.PAYMENT_TYPE_DEPOSIT &&
+		booking.Status != models.BOOKING_STATUS_PENDING && booking.Status != models.BOOKING_STATUS_CONFIRMED {
+		return "Deposits can only be taken before pickup"
+	}
+
+	if payment.Amount > balance.Outstanding {
+		return fmt.Sprintf("Payment of %s exceeds the outstanding balance of %s", payment.Amount, balance.Outstanding)
+	}
+
+	if payment.Type == models.PAYMENT_TYPE_SETTLEMENT && payment.Amount != balance.Outstanding {
+		return fmt.Sprintf("A settlement must pay the full outstanding balance of %s", balance.Outstanding)
+	}
+
+	return ""
+}
+
+func checkRefund(booking *models.Booking, balance *utils.BookingBalance, payment *models.Payment) string {
+	if payment.Amount > balance.NetPaid {
+		return fmt.Sprintf("Refund of %s exceeds the net amount paid of %s", payment.Amount, balance.NetPaid)
+	}
+	return ""
+}
+
+// recordPayment adds a ledger entry. The booking row is locked so two payments cannot both
+// be checked against the same balance.
+func recordPayment(c *gin.Context, payment *models.Payment, check paymentCheck) {
+	booking, status, err := findBookingByID(c)
+	if err != nil {
+		if status == http.StatusBadRequest {
+			c.JSON(status, gin.H{"error": "Invalid booking ID"})
+		} else {
+			c.JSON(status, gin.H{"error": "Booking not found"})
+		}
+		return
+	}
+
+	var balance *utils.BookingBalance
+	rejection := ""
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(booking, booking.No).Error; err != nil {
+			return err
+		}
+
+		current, err := utils.CalculateBookingBalance(tx, booking)
+		if err != nil {
+			return err
+		}
+
+		if rejection = check(booking, current, payment); rejection != "" {
+			return errPaymentRejected
+		}
+
+		payment.BookingID = booking.No
+		if err := tx.Create(payment).Error; err != nil {
+			return err
+		}
+
+		balance, err = utils.RefreshPaymentStatus(tx, booking)
+		return err
+	})
+	if errors.Is(err, errPaymentRejected) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": rejection})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record payment"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"data": payment, "balance": balance})
+}
+
+// requireSettledBalance is the close side effect: a booking is only closed once nothing is
+// owed either way
+func requireSettledBalance(tx *gorm.DB, booking *models.Booking) (int, string) {
+	balance, err := utils.CalculateBookingBalance(tx, booking)
+	if err != nil {
+		return http.StatusInternalServerError, "Failed to calculate balance"
+	}
+
+	if balance.Outstanding > 0 {
+		return http.StatusConflict, fmt.Sprintf("Booking cannot be closed while a balance of %s is outstanding", balance.Outstanding)
+	}
+	if balance.Outstanding < 0 {
+		return http.StatusConflict, fmt.Sprintf("Booking cannot be closed while a refund of %s is due", -balance.Outstanding)
+	}
+
+	return http.StatusOK, ""
+}
@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"car-rental/pkg/database"
+	"car-rental/pkg/models"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+func GetRateRules(c *gin.Context) {
+	query := database.DB.Preload("Car").Order("start_date, priority DESC")
+
+	// Optional filter on the car a rule applies to, including rules for every car
+	if carID := c.Query("car_id"); carID != "" {
+		id, err := strconv.Atoi(carID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid car ID"})
+			return
+		}
+		query = query.Where("car_id IS NULL OR car_id = ?", id)
+	}
+
+	var rules []models.RateRule
+	if err := query.Find(&rules).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve rate rules"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": rules})
+}
+
+// Helper function to find a rate rule by ID
+func findRateRuleByID(c *gin.Context) (*models.RateRule, int, error) {
+	id := c.Param("id")
+	ruleID, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	var rule models.RateRule
+	result := database.DB.Preload("Car").First(&rule, ruleID)
+	if result.Error != nil {
+		return nil, http.StatusNotFound, result.Error
+	}
+
+	return &rule, http.StatusOK, nil
+}
+
+// validateRateRule checks the parts of a rule the binding tags cannot express
+func validateRateRule(rule *models.RateRule) (int, string) {
+	if rule.EndDate.Before(rule.StartDate) {
+		return http.StatusBadRequest, "End date must not be before start date"
+	}
+
+	if (rule.Multiplier == nil) == (rule.FixedRate == nil) {
+		return http.StatusBadRequest, "Exactly one of multiplier or fixed_rate must be set"
+	}
+
+	if rule.CarID != nil {
+		var car models.Car
+		if err := database.DB.Where("deleted_at IS NULL").First(&car, *rule.CarID).Error; err != nil {
+			return http.StatusBadRequest, "Car not found or has been removed"
+		}
+	}
+
+	return http.StatusOK, ""
+}
+
+func GetRateRule(c *gin.Context) {
+	rule, status, err := findRateRuleByID(c)
+	if err != nil {
+		if status == http.StatusBadRequest {
+			c.JSON(status, gin.H{"error": "Invalid rate rule ID"})
+		} else {
+			c.JSON(status, gin.H{"error": "Rate rule not found"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": rule})
+}
+
+func CreateRateRule(c *gin.Context) {
+	var rule models.RateRule
+
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if status, message := validateRateRule(&rule); status != http.StatusOK {
+		c.JSON(status, gin.H{"error": message})
+		return
+	}
+
+	result := database.DB.Omit("Car").Create(&rule)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create rate rule"})
+		return
+	}
+
+	database.DB.Preload("Car").First(&rule, rule.No)
+
+	c.JSON(http.StatusCreated, gin.H{"data": rule})
+}
+
+func UpdateRateRule(c *gin.Context) {
+	rule, status, err := findRateRuleByID(c)
+	if err != nil {
+		if status == http.StatusBadRequest {
+			c.JSON(status, gin.H{"error": "Invalid rate rule ID"})
+		} else {
+			c.JSON(status, gin.H{"error": "Rate rule not found"})
+		}
+		return
+	}
+
+	var updateData models.RateRule
+	if err := c.ShouldBindJSON(&updateData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if status, message := validateRateRule(&updateData); status != http.StatusOK {
+		c.JSON(status, gin.H{"error": message})
+		return
+	}
+
+	// A rule is replaced as a whole so a multiplier can be swapped for a fixed rate and the car filter cleared
+	result := database.DB.Model(rule).
+		Select("name", "start_date", "end_date", "car_id", "multiplier", "fixed_rate", "priority").
+		Updates(updateData)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update rate rule"})
+		return
+	}
+
+	// Reload so the car relation matches the new filter
+	rule.Car = nil
+	database.DB.Preload("Car").First(rule, rule.No)
+
+	c.JSON(http.StatusOK, gin.H{"data": rule})
+}
+
+func DeleteRateRule(c *gin.Context) {
+	rule, status, err := findRateRuleByID(c)
+	if err != nil {
+		if status == http.StatusBadRequest {
+			c.JSON(status, gin.H{"error": "Invalid rate rule ID"})
+		} else {
+			c.JSON(status, gin.H{"error": "Rate rule not found"})
+		}
+		return
+	}
+
+	// Bookings keep the total they were priced at, so rules can be removed at any time
+	result := database.DB.Delete(rule)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete rate rule"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Rate rule deleted successfully"})
+}
@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"car-rental/pkg/database"
+	"car-rental/pkg/models"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+func GetTaxRates(c *gin.Context) {
+	var rates []models.TaxRate
+	if err := database.DB.Order("no").Find(&rates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve tax rates"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": rates})
+}
+
+// Helper function to find a tax rate by ID
+func findTaxRateByID(c *gin.Context) (*models.TaxRate, int, error) {
+	id := c.Param("id")
+	rateID, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	var rate models.TaxRate
+	result := database.DB.First(&rate, rateID)
+	if result.Error != nil {
+		return nil, http.StatusNotFound, result.Error
+	}
+
+	return &rate, http.StatusOK, nil
+}
+
+// validateTaxRate checks that a rate is charged on at least one part of the price
+func validateTaxRate(rate *models.TaxRate) (int, string) {
+	if !rate.AppliesToRental && !rate.AppliesToDriver && !rate.AppliesToFees {
+		return http.StatusBadRequest, "Tax rate must apply to at least one of rental, driver or fees"
+	}
+	return http.StatusOK, ""
+}
+
+func GetTaxRate(c *gin.Context) {
+	rate, status, err := findTaxRateByID(c)
+	if err != nil {
+		if status == http.StatusBadRequest {
+			c.JSON(status, gin.H{"error": "Invalid tax rate ID"})
+		} else {
+			c.JSON(status, gin.H{"error": "Tax rate not found"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": rate})
+}
+
+func CreateTaxRate(c *gin.Context) {
+	var rate models.TaxRate
+
+	if err := c.ShouldBindJSON(&rate); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if status, message := validateTaxRate(&rate); status != http.StatusOK {
+		c.JSON(status, gin.H{"error": message})
+		return
+	}
+
+	result := database.DB.Create(&rate)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create tax rate"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"data": rate})
+}
+
+func UpdateTaxRate(c *gin.Context) {
+	rate, status, err := findTaxRateByID(c)
+	if err != nil {
+		if status == http.StatusBadRequest {
+			c.JSON(status, gin.H{"error": "Invalid tax rate ID"})
+		} else {
+			c.JSON(status, gin.H{"error": "Tax rate not found"})
+		}
+		return
+	}
+
+	var updateData models.TaxRate
+	if err := c.ShouldBindJSON(&updateData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if status, message := validateTaxRate(&updateData); status != http.StatusOK {
+		c.JSON(status, gin.H{"error": message})
+		return
+	}
+
+	// A rate is replaced as a whole so its flags can be switched off
+	result := database.DB.Model(rate).
+		Select("name", "rate", "applies_to_rental", "applies_to_driver", "applies_to_fees", "applied_before_discount").
+		Updates(updateData)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update tax rate"})
+		return
+	}
+
+	database.DB.First(rate, rate.No)
+
+	c.JSON(http.StatusOK, gin.H{"data": rate})
+}
+
+func DeleteTaxRate(c *gin.Context) {
+	rate, status, err := findTaxRateByID(c)
+	if err != nil {
+		if status == http.StatusBadRequest {
+			c.JSON(status, gin.H{"error": "Invalid tax rate ID"})
+		} else {
+			c.JSON(status, gin.H{"error": "Tax rate not found"})
+		}
+		return
+	}
+
+	// Bookings keep the tax they were charged, so rates can be removed at any time
+	result := database.DB.Delete(rate)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete tax rate"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Tax rate deleted successfully"})
+}
@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"car-rental/pkg/auth"
+	"car-rental/pkg/database"
+	"car-rental/pkg/models"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func GetUsers(c *gin.Context) {
+	var users []models.User
+	if err := database.DB.Order("no").Find(&users).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve users"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": users})
+}
+
+// Helper function to find a user by ID
+func findUserByID(c *gin.Context) (*models.User, int, error) {
+	id := c.Param("id")
+	userID, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	var user models.User
+	result := database.DB.First(&user, userID)
+	if result.Error != nil {
+		return nil, http.StatusNotFound, result.Error
+	}
+
+	return &user, http.StatusOK, nil
+}
+
+// validateUserRole checks that driver and customer users are linked to an existing record,
+// and that other roles are not linked to any
+func validateUserRole(role string, driverID, customerID *int) (int, string) {
+	if role == models.ROLE_DRIVER {
+		if driverID == nil {
+			return http.StatusBadRequest, "driver_id is required for the driver role"
+		}
+		var driver models.Driver
+		if err := database.DB.Where("deleted_at IS NULL").First(&driver, *driverID).Error; err != nil {
+			return http.StatusBadRequest, "Driver not found or has been removed"
+		}
+	} else if driverID != nil {
+		return http.StatusBadRequest, "driver_id is only allowed for the driver role"
+	}
+
+	if role == models.ROLE_CUSTOMER {
+		if customerID == nil {
+			return http.StatusBadRequest, "customer_id is required for the customer role"
+		}
+		var customer models.Customer
+		if err := database.DB.Where("deleted_at IS NULL").First(&customer, *customerID).Error; err != nil {
+			return http.StatusBadRequest, "Customer not found or has been removed"
+		}
+	} else if customerID != nil {
+		return http.StatusBadRequest, "customer_id is only allowed for the customer role"
+	}
+
+	return http.StatusOK, ""
+}
+
+func GetUser(c *gin.Context) {
+	user, status, err := findUserByID(c)
+	if err != nil {
+		if status == http.StatusBadRequest {
+			c.JSON(status, gin.H{"error": "Invalid user ID"})
+		} else {
+			c.JSON(status, gin.H{"error": "User not found"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": user})
+}
+
+func CreateUser(c *gin.Context) {
+	var request models.UserRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if status, message := validateUserRole(request.Role, request.DriverID, request.CustomerID); status != http.StatusOK {
+		c.JSON(status, gin.H{"error": message})
+		return
+	}
+
+	user := models.User{
+		Username:   strings.ToLower(strings.TrimSpace(request.Username)),
+		Name:       request.Name,
+		Role:       request.Role,
+		DriverID:   request.DriverID,
+		CustomerID: request.CustomerID,
+		Active:     true,
+	}
+
+	var existing int64
+	database.DB.Model(&models.User{}).Where("username = ?", user.Username).Count(&existing)
+	if existing > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Username is already taken"})
+		return
+	}
+
+	hash, err := auth.HashPassword(request.Password)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		return
+	}
+	user.PasswordHash = hash
+
+	if err := database.DB.Create(&user).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"data": user})
+}
+
+// UpdateUser changes a user's name, password, role or active flag. A new password, a new
+// role or disabling the account revokes every refresh token of the user.
+func UpdateUser(c *gin.Context) {
+	user, status, err := findUserByID(c)
+	if err != nil {
+		if status == http.StatusBadRequest {
+			c.JSON(status, gin.H{"error": "Invalid user ID"})
+		} else {
+			c.JSON(status, gin.H{"error": "User not found"})
+		}
+		return
+	}
+
+	var updateData models.UserUpdate
+	if err := c.ShouldBindJSON(&updateData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Admins cannot lock themselves out
+	if claims := auth.CurrentClaims(c); claims != nil && claims.Subject == strconv.Itoa(user.No) {
+		if (updateData.Active != nil && !*updateData.Active) || (updateData.Role != nil && *updateData.Role != user.Role) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot disable your own account or change your own role"})
+			return
+		}
+	}
+
+	updates := map[string]interface{}{}
+	if updateData.Role != nil {
+		if status, message := validateUserRole(*updateData.Role, updateData.DriverID, updateData.CustomerID); status != http.StatusOK {
+			c.JSON(status, gin.H{"error": message})
+			return
+		}
+		updates["role"] = *updateData.Role
+		updates["driver_id"] = updateData.DriverID
+		updates["customer_id"] = updateData.CustomerID
+	} else if updateData.DriverID != nil || updateData.CustomerID != nil {
+		if status, message := validateUserRole(user.Role, updateData.DriverID, updateData.CustomerID); status != http.StatusOK {
+			c.JSON(status, gin.H{"error": message})
+			return
+		}
+		updates["driver_id"] = updateData.DriverID
+		updates["customer_id"] = updateData.CustomerID
+	}
+	if updateData.Name != nil {
+		updates["name"] = *updateData.Name
+	}
+	if updateData.Active != nil {
+		updates["active"] = *updateData.Active
+	}
+	if updateData.Password != nil {
+		hash, err := auth.HashPassword(*updateData.Password)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+			return
+		}
+		updates["password_hash"] = hash
+	}
+	_, relinked := updates["driver_id"]
+	signOut := updateData.Password != nil || relinked || (updateData.Active != nil && !*updateData.Active)
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if len(updates) > 0 {
+			if err := tx.Model(user).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+		if signOut {
+			return auth.RevokeUserTokens(tx, user.No, time.Now())
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user"})
+		return
+	}
+
+	database.DB.First(user, user.No)
+
+	c.JSON(http.StatusOK, gin.H{"data": user})
+}
@@ -0,0 +1,257 @@
+package handlers
+
+import (
+	"car-rental/pkg/database"
+	"car-rental/pkg/models"
+	"car-rental/pkg/utils"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func GetVouchers(c *gin.Context) {
+	var vouchers []models.Voucher
+	result := database.DB.Where("deleted_at IS NULL").Preload("Cars").Order("no").Find(&vouchers)
+
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve vouchers"})
+		return
+	}
+
+	for i := range vouchers {
+		fillVoucherCarIDs(&vouchers[i])
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": vouchers})
+}
+
+// Helper function to find a voucher by ID
+func findVoucherByID(c *gin.Context) (*models.Voucher, int, error) {
+	id := c.Param("id")
+	voucherID, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	var voucher models.Voucher
+	result := database.DB.Where("deleted_at IS NULL").Preload("Cars").First(&voucher, voucherID)
+	if result.Error != nil {
+		return nil, http.StatusNotFound, result.Error
+	}
+
+	fillVoucherCarIDs(&voucher)
+	return &voucher, http.StatusOK, nil
+}
+
+func fillVoucherCarIDs(voucher *models.Voucher) {
+	voucher.CarIDs = make([]int, 0, len(voucher.Cars))
+	for _, car := range voucher.Cars {
+		voucher.CarIDs = append(voucher.CarIDs, car.No)
+	}
+}
+
+// loadVoucherCars validates the voucher's window and loads the cars it is restricted to
+func loadVoucherCars(voucher *models.Voucher) (int, string) {
+	if voucher.ValidUntil.Before(voucher.ValidFrom) {
+		return http.StatusBadRequest, "Valid until must not be before valid from"
+	}
+
+	// Each type keeps its discount in its own field, so a percentage is never read as rupiah
+	switch voucher.DiscountType {
+	case models.VOUCHER_TYPE_PERCENT:
+		if voucher.Percent <= 0 || voucher.Amount != 0 {
+			return http.StatusBadRequest, "A percentage voucher needs a percent and no amount"
+		}
+	case models.VOUCHER_TYPE_FIXED:
+		if voucher.Amount <= 0 || voucher.Percent != 0 {
+			return http.StatusBadRequest, "A fixed voucher needs an amount and no percent"
+		}
+	}
+
+	voucher.Cars = []models.Car{}
+	if len(voucher.CarIDs) > 0 {
+		if err := database.DB.Where("deleted_at IS NULL AND no IN ?", voucher.CarIDs).Find(&voucher.Cars).Error; err != nil {
+			return http.StatusInternalServerError, "Failed to load voucher cars"
+		}
+		if len(voucher.Cars) != len(voucher.CarIDs) {
+			return http.StatusBadRequest, "Car not found or has been removed"
+		}
+	}
+
+	return http.StatusOK, ""
+}
+
+func GetVoucher(c *gin.Context) {
+	voucher, status, err := findVoucherByID(c)
+	if err != nil {
+		if status == http.StatusBadRequest {
+			c.JSON(status, gin.H{"error": "Invalid voucher ID"})
+		} else {
+			c.JSON(status, gin.H{"error": "Voucher not found"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": voucher})
+}
+
+func CreateVoucher(c *gin.Context) {
+	var voucher models.Voucher
+
+	if err := c.ShouldBindJSON(&voucher); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	voucher.Code = utils.NormalizeVoucherCode(voucher.Code)
+	if status, message := loadVoucherCars(&voucher); status != http.StatusOK {
+		c.JSON(status, gin.H{"error": message})
+		return
+	}
+
+	var existing int64
+	database.DB.Model(&models.Voucher{}).Where("code = ?", voucher.Code).Count(&existing)
+	if existing > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Voucher code already exists"})
+		return
+	}
+
+	result := database.DB.Create(&voucher)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create voucher"})
+		return
+	}
+
+	fillVoucherCarIDs(&voucher)
+	c.JSON(http.StatusCreated, gin.H{"data": voucher})
+}
+
+func UpdateVoucher(c *gin.Context) {
+	voucher, status, err := findVoucherByID(c)
+	if err != nil {
+		if status == http.StatusBadRequest {
+			c.JSON(status, gin.H{"error": "Invalid voucher ID"})
+		} else {
+			c.JSON(status, gin.H{"error": "Voucher not found"})
+		}
+		return
+	}
+
+	var updateData models.Voucher
+	if err := c.ShouldBindJSON(&updateData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// The code is what customers were given, so it cannot change once created
+	updateData.Code = voucher.Code
+	if status, message := loadVoucherCars(&updateData); status != http.StatusOK {
+		c.JSON(status, gin.H{"error": message})
+		return
+	}
+
+	// Every field is replaced so limits can be lifted and stacking switched off
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(voucher).
+			Select("description", "discount_type", "amount", "percent", "valid_from", "valid_until", "usage_limit",
+				"per_customer_limit", "min_rental_days", "stack_with_membership").
+			Updates(updateData).Error
+		if err != nil {
+			return err
+		}
+		return tx.Model(voucher).Association("Cars").Replace(updateData.Cars)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update voucher"})
+		return
+	}
+
+	voucher, _, _ = findVoucherByID(c)
+	c.JSON(http.StatusOK, gin.H{"data": voucher})
+}
+
+func DeleteVoucher(c *gin.Context) {
+	voucher, status, err := findVoucherByID(c)
+	if err != nil {
+		if status == http.StatusBadRequest {
+			c.JSON(status, gin.H{"error": "Invalid voucher ID"})
+		} else {
+			c.JSON(status, gin.H{"error": "Voucher not found"})
+		}
+		return
+	}
+
+	// Soft delete keeps the redemptions of past bookings readable
+	if err := utils.SoftDeleteVoucher(voucher.No); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to soft delete voucher"})
+		return
+	}
+
+	utils.RespondWithSoftDeleteSuccess(c, "voucher", voucher.No)
+}
+
+// GetVoucherRedemptions lists the bookings a voucher was used on
+func GetVoucherRedemptions(c *gin.Context) {
+	voucher, status, err := findVoucherByID(c)
+	if err != nil {
+		if status == http.StatusBadRequest {
+			c.JSON(status, gin.H{"error": "Invalid voucher ID"})
+		} else {
+			c.JSON(status, gin.H{"error": "Voucher not found"})
+		}
+		return
+	}
+
+	var redemptions []models.VoucherRedemption
+	if err := database.DB.Where("voucher_id = ?", voucher.No).Order("redeemed_at DESC").Find(&redemptions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve voucher redemptions"})
+		return
+	}
+
+	active := 0
+	for _, redemption := range redemptions {
+		if redemption.ReleasedAt == nil {
+			active++
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":        redemptions,
+		"voucher":     voucher,
+		"times_used":  active,
+		"usage_limit": voucher.UsageLimit,
+	})
+}
+
+// redeemVoucher records a voucher use on a new booking. The voucher row is locked so two
+// bookings cannot take its last use at the same time.
+func redeemVoucher(tx *gorm.DB, voucher *models.Voucher, booking *models.Booking) (int, string) {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&models.Voucher{}, voucher.No).Error; err != nil {
+		return http.StatusInternalServerError, "Failed to lock voucher"
+	}
+
+	message, err := utils.CheckVoucherUsage(tx, voucher, booking.CustomerID)
+	if err != nil {
+		return http.StatusInternalServerError, "Failed to check voucher"
+	}
+	if message != "" {
+		return http.StatusBadRequest, message
+	}
+
+	redemption := models.VoucherRedemption{
+		VoucherID:  voucher.No,
+		BookingID:  booking.No,
+		CustomerID: booking.CustomerID,
+		Discount:   booking.VoucherDiscount,
+		RedeemedAt: time.Now(),
+	}
+	if err := tx.Create(&redemption).Error; err != nil {
+		return http.StatusInternalServerError, "Failed to record voucher redemption"
+	}
+
+	return http.StatusOK, ""
+}
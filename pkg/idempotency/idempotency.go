@@ -0,0 +1,286 @@
+// Package idempotency lets clients retry unsafe requests. A request sent with an
+// Idempotency-Key header is stored with a fingerprint of its method, URL and body and the
+// response it got; a retry with the same key gets that response back without running again.
+package idempotency
+
+import (
+	"bytes"
+	"car-rental/pkg/auth"
+	"car-rental/pkg/models"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Idempotency settings
+const (
+	HEADER             = "Idempotency-Key"
+	REPLAYED_HEADER    = "Idempotent-Replayed" // Set to "true" on replayed responses
+	MAX_KEY_LENGTH     = 255
+	DEFAULT_TTL        = 24 * time.Hour
+	HEARTBEAT_INTERVAL = 15 * time.Second // How often a running request shows it is still alive
+	PROCESSING_TIMEOUT = time.Minute      // A running request without a heartbeat for this long has died
+)
+
+// Store keeps idempotency keys in the database so every node sees them
+type Store struct {
+	db  *gorm.DB
+	ttl time.Duration
+}
+
+// Default is the store used by the routes, set up in main
+var Default *Store
+
+func New(db *gorm.DB, ttl time.Duration) *Store {
+	return &Store{db: db, ttl: ttl}
+}
+
+// TTL returns how long keys are kept
+func (s *Store) TTL() time.Duration {
+	return s.ttl
+}
+
+// Middleware makes a route idempotent for requests that send an Idempotency-Key. Requests
+// without one run as usual. Put it after the authentication middleware, since keys are
+// scoped to the caller.
+func (s *Store) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := strings.TrimSpace(c.GetHeader(HEADER))
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > MAX_KEY_LENGTH {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key must be at most 255 characters"})
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		record, err := s.claim(auth.ClientKey(c), key, Fingerprint(c.Request.Method, c.Request.URL.RequestURI(), body), time.Now())
+		var conflict *conflictError
+		switch {
+		case errors.As(err, &conflict):
+			if conflict.inProgress {
+				c.Header("Retry-After", "1")
+			}
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": conflict.message})
+			return
+		case err != nil:
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to check idempotency key"})
+			return
+		case record.CompletedAt != nil:
+			replayHeaders(c, record.ResponseHeaders)
+			c.Header(REPLAYED_HEADER, "true")
+			c.Data(record.StatusCode, record.ContentType, record.ResponseBody)
+			c.Abort()
+			return
+		}
+
+		// Headers set before this point belong to the other middleware, not the response
+		before := c.Writer.Header().Clone()
+		stopHeartbeat := s.heartbeat(record)
+		defer stopHeartbeat()
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+		stopHeartbeat()
+
+		// Server errors roll back, so the key is freed for a retry that can still succeed
+		status := recorder.Status()
+		if status >= http.StatusInternalServerError {
+			if err := s.db.Delete(record).Error; err != nil {
+				log.Printf("Failed to free idempotency key %q of %s after a %d response: %v", record.Key, record.Scope, status, err)
+			}
+			return
+		}
+
+		headers, err := json.Marshal(handlerHeaders(before, recorder.Header()))
+		if err == nil {
+			err = s.db.Model(record).Updates(map[string]interface{}{
+				"status_code":      status,
+				"content_type":     recorder.Header().Get("Content-Type"),
+				"response_body":    recorder.body.Bytes(),
+				"response_headers": string(headers),
+				"completed_at":     time.Now(),
+			}).Error
+		}
+		if err != nil {
+			// The key stays unfinished, so retries get 409 until PROCESSING_TIMEOUT has passed
+			// since the last heartbeat and may then run the request again
+			log.Printf("Failed to store the %d response of idempotency key %q of %s: %v", status, record.Key, record.Scope, err)
+		}
+	}
+}
+
+// heartbeat keeps refreshing a claimed key while its request runs, so other nodes never take
+// over a request that is slow but alive. The returned function stops it and may be called twice.
+func (s *Store) heartbeat(record *models.IdempotencyKey) func() {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(HEARTBEAT_INTERVAL)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case now := <-ticker.C:
+				err := s.db.Model(&models.IdempotencyKey{}).
+					Where("no = ? AND completed_at IS NULL", record.No).
+					Update("heartbeat_at", now).Error
+				if err != nil {
+					log.Printf("Failed to refresh idempotency key %q of %s: %v", record.Key, record.Scope, err)
+				}
+			}
+		}
+	}()
+
+	stopped := false
+	return func() {
+		if !stopped {
+			stopped = true
+			close(done)
+		}
+	}
+}
+
+// handlerHeaders returns the response headers the handler set or changed, such as ETag and
+// Location. The body's type and length are stored and written separately.
+func handlerHeaders(before, after http.Header) http.Header {
+	headers := http.Header{}
+	for name, values := range after {
+		if name == "Content-Type" || name == "Content-Length" || slices.Equal(before[name], values) {
+			continue
+		}
+		headers[name] = values
+	}
+	return headers
+}
+
+// replayHeaders writes the stored handler headers of a finished request
+func replayHeaders(c *gin.Context, stored string) {
+	if stored == "" {
+		return
+	}
+
+	var headers http.Header
+	if err := json.Unmarshal([]byte(stored), &headers); err != nil {
+		log.Printf("Failed to read stored idempotency response headers: %v", err)
+		return
+	}
+	for name, values := range headers {
+		c.Writer.Header()[name] = values
+	}
+}
+
+// Fingerprint identifies a request by its method, URL with its query string and exact body
+func Fingerprint(method, uri string, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(method + " " + uri + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+type conflictError struct {
+	message    string
+	inProgress bool
+}
+
+func (e *conflictError) Error() string {
+	return e.message
+}
+
+// claim stores a new key, or returns the stored one. A completed record is replayed; an
+// expired one, or one whose request stopped sending heartbeats, is taken over by this request.
+func (s *Store) claim(scope, key, fingerprint string, now time.Time) (*models.IdempotencyKey, error) {
+	record := models.IdempotencyKey{
+		Scope:       scope,
+		Key:         key,
+		Fingerprint: fingerprint,
+		CreatedAt:   now,
+		HeartbeatAt: now,
+		ExpiresAt:   now.Add(s.ttl),
+	}
+
+	// Only one of several concurrent requests with the same key gets to insert it
+	result := s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&record)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 1 {
+		return &record, nil
+	}
+
+	var existing models.IdempotencyKey
+	if err := s.db.Where("scope = ? AND key = ?", scope, key).First(&existing).Error; err != nil {
+		return nil, err
+	}
+
+	expired := !now.Before(existing.ExpiresAt)
+	abandoned := existing.CompletedAt == nil && now.Sub(existing.HeartbeatAt) > PROCESSING_TIMEOUT
+	switch {
+	case !expired && existing.Fingerprint != fingerprint:
+		return nil, &conflictError{message: "Idempotency-Key was already used with a different request"}
+	case !expired && existing.CompletedAt != nil:
+		return &existing, nil
+	case !expired && !abandoned:
+		return nil, &conflictError{message: "A request with this Idempotency-Key is still being processed", inProgress: true}
+	}
+
+	// Taken over only if no other retry got there first
+	result = s.db.Model(&models.IdempotencyKey{}).
+		Where("no = ? AND heartbeat_at = ?", existing.No, existing.HeartbeatAt).
+		Updates(map[string]interface{}{
+			"fingerprint":      fingerprint,
+			"status_code":      0,
+			"content_type":     "",
+			"response_body":    nil,
+			"response_headers": "",
+			"created_at":       now,
+			"heartbeat_at":     now,
+			"completed_at":     nil,
+			"expires_at":       now.Add(s.ttl),
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, &conflictError{message: "A request with this Idempotency-Key is still being processed", inProgress: true}
+	}
+
+	record.No = existing.No
+	return &record, nil
+}
+
+// responseRecorder keeps a copy of the response body while writing it to the client
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *responseRecorder) WriteString(s string) (int, error) {
+	r.body.WriteString(s)
+	return r.ResponseWriter.WriteString(s)
+}
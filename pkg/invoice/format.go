@@ -0,0 +1,39 @@
+// Package invoice renders issued invoices as HTML pages and PDF files
+package invoice
+
+import (
+	"car-rental/pkg/models"
+	"car-rental/pkg/money"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// FormatAmount writes an amount with thousands separators and two decimals, e.g. 1,250,000.00
+func FormatAmount(amount money.Amount) string {
+	s := amount.String()
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+
+	whole, cents, _ := strings.Cut(s, ".")
+	var b strings.Builder
+	for i, digit := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(digit)
+	}
+	return sign + b.String() + "." + cents
+}
+
+// ConvertedTotalLabel names the converted total of an invoice, e.g. "Total in USD at IDR 16250"
+func ConvertedTotalLabel(invoice *models.Invoice) string {
+	return fmt.Sprintf("Total in %s at %s %s", *invoice.Currency, models.BASE_CURRENCY, invoice.ExchangeRate)
+}
+
+// FormatDate writes a date and time the way it is printed on invoices
+func FormatDate(t time.Time) string {
+	return t.Format("02 Jan 2006 15:04")
+}
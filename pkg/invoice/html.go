@@ -0,0 +1,67 @@
+package invoice
+
+import (
+	"car-rental/pkg/models"
+	"html/template"
+	"io"
+)
+
+var htmlTemplate = template.Must(template.New("invoice").Funcs(template.FuncMap{
+	"amount":         FormatAmount,
+	"date":           FormatDate,
+	"convertedLabel": ConvertedTotalLabel,
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Invoice {{.Number}}</title>
+<style>
+body { font-family: Helvetica, Arial, sans-serif; color: #222; margin: 40px; }
+h1 { font-size: 24px; margin-bottom: 4px; }
+table { border-collapse: collapse; width: 100%; margin-top: 24px; }
+th, td { padding: 6px 8px; text-align: left; }
+.items th { border-bottom: 2px solid #222; }
+.items td { border-bottom: 1px solid #ddd; }
+.amount { text-align: right; white-space: nowrap; }
+.totals td { border: none; }
+.grand td { font-weight: bold; border-top: 2px solid #222; }
+</style>
+</head>
+<body>
+<h1>Invoice {{.Number}}</h1>
+<div>Issued {{date .IssuedAt}}</div>
+
+<table>
+<tr><th>Customer</th><td>{{.CustomerName}}</td></tr>
+<tr><th>NIK</th><td>{{.CustomerNIK}}</td></tr>
+<tr><th>Car</th><td>{{.CarName}}</td></tr>
+<tr><th>Rental period</th><td>{{date .StartRent}} – {{date .EndRent}}</td></tr>
+{{- if .ReturnedAt}}
+<tr><th>Returned</th><td>{{date .ReturnedAt}}</td></tr>
+{{- end}}
+</table>
+
+<table class="items">
+<tr><th>#</th><th>Description</th><th class="amount">Amount</th></tr>
+{{- range .Lines}}
+<tr><td>{{.Position}}</td><td>{{.Description}}</td><td class="amount">{{amount .Amount}}</td></tr>
+{{- end}}
+</table>
+
+<table class="totals">
+<tr><td></td><td class="amount">Subtotal</td><td class="amount">{{amount .Subtotal}}</td></tr>
+<tr><td></td><td class="amount">Discounts</td><td class="amount">{{amount .DiscountTotal}}</td></tr>
+<tr><td></td><td class="amount">Tax</td><td class="amount">{{amount .TaxTotal}}</td></tr>
+<tr class="grand"><td></td><td class="amount">Total</td><td class="amount">{{amount .Total}}</td></tr>
+{{- if .ConvertedTotal}}
+<tr><td></td><td class="amount">{{convertedLabel .}}</td><td class="amount">{{amount .ConvertedTotal}}</td></tr>
+{{- end}}
+</table>
+</body>
+</html>
+`))
+
+// RenderHTML writes the invoice as a printable HTML page
+func RenderHTML(w io.Writer, invoice *models.Invoice) error {
+	return htmlTemplate.Execute(w, invoice)
+}
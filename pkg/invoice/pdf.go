@@ -0,0 +1,156 @@
+package invoice
+
+import (
+	"bytes"
+	"car-rental/pkg/models"
+	"fmt"
+	"strings"
+)
+
+// A4 page size and margins in PDF points
+const (
+	pageWidth   = 595
+	pageHeight  = 842
+	marginLeft  = 50
+	marginRight = 545
+)
+
+// RenderPDF lays the invoice out on a single A4 page. The file is written by hand with
+// the standard Helvetica and Courier fonts, so no font files are embedded.
+func RenderPDF(invoice *models.Invoice) []byte {
+	page := &pdfPage{y: pageHeight - 60}
+
+	page.text("F2", 20, marginLeft, "Invoice "+invoice.Number)
+	page.newline(18)
+	page.text("F1", 10, marginLeft, "Issued "+FormatDate(invoice.IssuedAt))
+	page.newline(30)
+
+	details := [][2]string{
+		{"Customer", invoice.CustomerName},
+		{"NIK", invoice.CustomerNIK},
+		{"Car", invoice.CarName},
+		{"Rental period", FormatDate(invoice.StartRent) + " - " + FormatDate(invoice.EndRent)},
+	}
+	if invoice.ReturnedAt != nil {
+		details = append(details, [2]string{"Returned", FormatDate(*invoice.ReturnedAt)})
+	}
+	for _, detail := range details {
+		page.text("F2", 10, marginLeft, detail[0])
+		page.text("F1", 10, marginLeft+110, detail[1])
+		page.newline(16)
+	}
+	page.newline(16)
+
+	page.text("F2", 10, marginLeft, "#")
+	page.text("F2", 10, marginLeft+30, "Description")
+	page.amount("F4", 10, "Amount")
+	page.newline(6)
+	page.rule(1)
+	page.newline(16)
+	for _, line := range invoice.Lines {
+		page.text("F1", 10, marginLeft, fmt.Sprint(line.Position))
+		page.text("F1", 10, marginLeft+30, line.Description)
+		page.amount("F3", 10, FormatAmount(line.Amount))
+		page.newline(18)
+	}
+	page.rule(0.5)
+	page.newline(18)
+
+	totals := [][2]string{
+		{"Subtotal", FormatAmount(invoice.Subtotal)},
+		{"Discounts", FormatAmount(invoice.DiscountTotal)},
+		{"Tax", FormatAmount(invoice.TaxTotal)},
+	}
+	for _, total := range totals {
+		page.text("F1", 10, marginRight-220, total[0])
+		page.amount("F3", 10, total[1])
+		page.newline(16)
+	}
+	page.text("F2", 12, marginRight-220, "Total")
+	page.amount("F4", 12, FormatAmount(invoice.Total))
+	if invoice.ConvertedTotal != nil {
+		page.newline(18)
+		page.text("F1", 10, marginRight-220, ConvertedTotalLabel(invoice))
+		page.amount("F3", 10, FormatAmount(*invoice.ConvertedTotal))
+	}
+
+	return page.document(invoice.Number)
+}
+
+// pdfPage collects the drawing operators of one page
+type pdfPage struct {
+	content bytes.Buffer
+	y       float64
+}
+
+func (p *pdfPage) newline(height float64) {
+	p.y -= height
+}
+
+func (p *pdfPage) text(font string, size float64, x float64, s string) {
+	fmt.Fprintf(&p.content, "BT /%s %.0f Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, p.y, pdfString(s))
+}
+
+// amount right-aligns text against the right margin. It is only used with the monospaced
+// Courier fonts (F3, F4), whose glyphs are all 0.6 em wide.
+func (p *pdfPage) amount(font string, size float64, s string) {
+	width := float64(len(s)) * size * 0.6
+	p.text(font, size, marginRight-width, s)
+}
+
+func (p *pdfPage) rule(width float64) {
+	fmt.Fprintf(&p.content, "%.1f w %d %.2f m %d %.2f l S\n", width, marginLeft, p.y, marginRight, p.y)
+}
+
+// document wraps the page in the objects of a minimal PDF file
+func (p *pdfPage) document(title string) []byte {
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 4 0 R /F2 5 0 R /F3 6 0 R /F4 7 0 R >> >> /Contents 8 0 R >>", pageWidth, pageHeight),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Courier-Bold /Encoding /WinAnsiEncoding >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", p.content.Len(), p.content.String()),
+		fmt.Sprintf("<< /Title (%s) /Producer (car-rental) >>", pdfString(title)),
+	}
+
+	var out bytes.Buffer
+	out.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = out.Len()
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, len(objects), xref)
+	return out.Bytes()
+}
+
+// pdfString escapes a string literal and maps it to WinAnsi, replacing characters the
+// standard fonts cannot show
+func pdfString(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '–' || r == '—':
+			b.WriteByte('-')
+		case r >= 0x20 && r < 0x7f:
+			b.WriteRune(r)
+		case r >= 0xa0 && r <= 0xff:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
@@ -1,37 +1,179 @@
 package models
 
 import (
+	"car-rental/pkg/money"
 	"time"
 )
 
+// Booking status constants
+const (
+	BOOKING_STATUS_PENDING   = "pending"   // Reservation made
+	BOOKING_STATUS_CONFIRMED = "confirmed" // Deposit received
+	BOOKING_STATUS_PICKED_UP = "picked_up" // Vehicle handed to the customer
+	BOOKING_STATUS_RETURNED  = "returned"  // Vehicle back at the branch
+	BOOKING_STATUS_CLOSED    = "closed"    // Settled, nothing left to do
+	BOOKING_STATUS_CANCELLED = "cancelled"
+)
+
+// BookingTransitions lists the statuses a booking may move to from each status
+var BookingTransitions = map[string][]string{
+	BOOKING_STATUS_PENDING:   {BOOKING_STATUS_CONFIRMED, BOOKING_STATUS_CANCELLED},
+	BOOKING_STATUS_CONFIRMED: {BOOKING_STATUS_PICKED_UP, BOOKING_STATUS_CANCELLED},
+	BOOKING_STATUS_PICKED_UP: {BOOKING_STATUS_RETURNED},
+	BOOKING_STATUS_RETURNED:  {BOOKING_STATUS_CLOSED},
+	BOOKING_STATUS_CLOSED:    {},
+	BOOKING_STATUS_CANCELLED: {},
+}
+
+// ActiveBookingStatuses are the statuses in which a booking still holds a car
+var ActiveBookingStatuses = []string{BOOKING_STATUS_PENDING, BOOKING_STATUS_CONFIRMED, BOOKING_STATUS_PICKED_UP}
+
+// CanTransitionBooking reports whether a booking may move from one status to another
+func CanTransitionBooking(from, to string) bool {
+	for _, allowed := range BookingTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// IsActiveBookingStatus reports whether a booking in this status still holds a car
+func IsActiveBookingStatus(status string) bool {
+	for _, active := range ActiveBookingStatuses {
+		if active == status {
+			return true
+		}
+	}
+	return false
+}
+
 type Booking struct {
-	No              int       `json:"no" gorm:"primaryKey;column:no;autoIncrement"`
-	CustomerID      int       `json:"customer_id" binding:"required" gorm:"column:customer_id;not null"`
-	CarsID          int       `json:"cars_id" binding:"required" gorm:"column:cars_id;not null"`
-	StartRent       time.Time `json:"start_rent" binding:"required" gorm:"column:start_rent;not null"`
-	EndRent         time.Time `json:"end_rent" binding:"required" gorm:"column:end_rent;not null"`
-	TotalCost       float64   `json:"total_cost" gorm:"column:total_cost;not null"`
-	Finished        bool      `json:"finished" gorm:"column:finished;default:false"`
-	Discount        float64   `json:"discount" gorm:"column:discount;default:0"`
-	BookingTypeID   int       `json:"booking_type_id" binding:"required" gorm:"column:booking_type_id;not null"`
-	DriverID        *int      `json:"driver_id" gorm:"column:driver_id"`
-	TotalDriverCost float64   `json:"total_driver_cost" gorm:"column:total_driver_cost;default:0"`
+	No              int          `json:"no" gorm:"primaryKey;column:no;autoIncrement"`
+	CustomerID      int          `json:"customer_id" binding:"required" gorm:"column:customer_id;not null"`
+	CarsID          int          `json:"cars_id" binding:"required" gorm:"column:cars_id;not null"`
+	StartRent       time.Time    `json:"start_rent" binding:"required" gorm:"column:start_rent;not null"`
+	EndRent         time.Time    `json:"end_rent" binding:"required" gorm:"column:end_rent;not null"`
+	TotalCost       money.Amount `json:"total_cost" gorm:"column:total_cost;not null"`
+	Finished        bool         `json:"finished" binding:"-" gorm:"column:finished;default:false"` // Kept in sync with Status for v1 clients
+	Status          string       `json:"status" binding:"-" gorm:"column:status;not null;default:pending;index"`
+	Discount        money.Amount `json:"discount" gorm:"column:discount;default:0"`
+	BookingTypeID   int          `json:"booking_type_id" binding:"required" gorm:"column:booking_type_id;not null"`
+	DriverID        *int         `json:"driver_id" gorm:"column:driver_id"`
+	TotalDriverCost money.Amount `json:"total_driver_cost" gorm:"column:total_driver_cost;default:0"`
+	CarUnitID       *int         `json:"car_unit_id" binding:"-" gorm:"column:car_unit_id;index"`
+	PromoCode       *string      `json:"promo_code" binding:"omitempty,max=32" gorm:"column:promo_code;size:32"`
+	VoucherDiscount money.Amount `json:"voucher_discount" binding:"-" gorm:"column:voucher_discount;default:0"`
+	RentalTax       money.Amount `json:"rental_tax" binding:"-" gorm:"column:rental_tax;default:0"`
+	DriverTax       money.Amount `json:"driver_tax" binding:"-" gorm:"column:driver_tax;default:0"`
+	PaymentStatus   string       `json:"payment_status" binding:"-" gorm:"column:payment_status;not null;default:unpaid"` // Refreshed whenever the ledger or the total changes
+	Version         int          `json:"version" binding:"-" gorm:"column:version;not null;default:1;<-:create"`          // Bumped by the database on every change, sent as the ETag
+
+	CreatedAt        *time.Time `json:"created_at,omitempty" binding:"-" gorm:"column:created_at;autoCreateTime"`
+	Overdue          bool       `json:"overdue" binding:"-" gorm:"column:overdue;default:false;index"`
+	OverdueFlaggedAt *time.Time `json:"overdue_flagged_at,omitempty" binding:"-" gorm:"column:overdue_flagged_at"`
+
+	ReturnedAt        *time.Time   `json:"returned_at,omitempty" binding:"-" gorm:"column:returned_at"`
+	OverdueHours      int          `json:"overdue_hours" binding:"-" gorm:"column:overdue_hours;default:0"`
+	LateFeeMultiplier money.Rate   `json:"late_fee_multiplier" binding:"-" gorm:"column:late_fee_multiplier;default:0"`
+	OverdueFee        money.Amount `json:"overdue_fee" binding:"-" gorm:"column:overdue_fee;default:0"`
+	FeeTax            money.Amount `json:"fee_tax" binding:"-" gorm:"column:fee_tax;default:0"`
+
+	CancelledAt            *time.Time   `json:"cancelled_at,omitempty" binding:"-" gorm:"column:cancelled_at"`
+	CancellationReason     string       `json:"cancellation_reason,omitempty" binding:"-" gorm:"column:cancellation_reason"`
+	CancellationFeePercent money.Rate   `json:"cancellation_fee_percent" binding:"-" gorm:"column:cancellation_fee_percent;default:0"`
+	CancellationFee        money.Amount `json:"cancellation_fee" binding:"-" gorm:"column:cancellation_fee;default:0"`
+	CancellationFeeTax     money.Amount `json:"cancellation_fee_tax" binding:"-" gorm:"column:cancellation_fee_tax;default:0"`
+
+	// Exchange rate recorded when the booking is created with ?currency=, reused for its invoice
+	Currency       *string     `json:"currency" binding:"-" gorm:"column:currency;size:3"`
+	ExchangeRateID *int        `json:"exchange_rate_id" binding:"-" gorm:"column:exchange_rate_id"`
+	ExchangeRate   *money.Rate `json:"exchange_rate" binding:"-" gorm:"column:exchange_rate"`
+
+	// Partner whose API key created the booking; null for bookings made by staff or customers
+	PartnerID *int `json:"partner_id" binding:"-" gorm:"column:partner_id;index"`
+
+	// Converted is only filled on responses requested with ?currency=
+	Converted *CurrencyConversion `json:"converted,omitempty" gorm:"-" binding:"-"`
 
 	Customer    Customer    `json:"customer,omitempty" gorm:"foreignKey:CustomerID;references:No" binding:"-"`
 	Car         Car         `json:"car,omitempty" gorm:"foreignKey:CarsID;references:No" binding:"-"`
 	Driver      *Driver     `json:"driver,omitempty" gorm:"foreignKey:DriverID;references:No" binding:"-"`
+	CarUnit     *CarUnit    `json:"car_unit,omitempty" gorm:"foreignKey:CarUnitID;references:No" binding:"-"`
 	BookingType BookingType `json:"booking_type,omitempty" gorm:"foreignKey:BookingTypeID;references:No" binding:"-"`
 }
 
+// BookingRequest holds the client supplied fields used to price and create a booking
+type BookingRequest struct {
+	CustomerID    int       `json:"customer_id" binding:"required"`
+	CarsID        int       `json:"cars_id" binding:"required"`
+	StartRent     time.Time `json:"start_rent" binding:"required"`
+	EndRent       time.Time `json:"end_rent" binding:"required"`
+	BookingTypeID int       `json:"booking_type_id" binding:"required"`
+	DriverID      *int      `json:"driver_id"`
+	PromoCode     *string   `json:"promo_code" binding:"omitempty,max=32"`
+	PartnerID     *int      `json:"-"` // Set by the partner routes, never by the client
+}
+
+// MyBookingRequest is the body of POST /me/bookings and POST /partner/bookings; the customer
+// is the signed-in one, or the partner's
+type MyBookingRequest struct {
+	CarsID        int       `json:"cars_id" binding:"required"`
+	StartRent     time.Time `json:"start_rent" binding:"required"`
+	EndRent       time.Time `json:"end_rent" binding:"required"`
+	BookingTypeID int       `json:"booking_type_id" binding:"required"`
+	DriverID      *int      `json:"driver_id"`
+	PromoCode     *string   `json:"promo_code" binding:"omitempty,max=32"`
+}
+
+// MyBookingUpdate is the body of PUT /me/bookings/:id. Customers can only move the dates;
+// the price is always recalculated.
+type MyBookingUpdate struct {
+	StartRent *time.Time `json:"start_rent,omitempty"`
+	EndRent   *time.Time `json:"end_rent,omitempty"`
+}
+
+// BookingUpdate is the body of PUT /bookings/:id. Only the dates come from the client;
+// the amounts are filled by the server, which always recalculates the price.
 type BookingUpdate struct {
-	StartRent       *time.Time `json:"start_rent,omitempty"`
-	EndRent         *time.Time `json:"end_rent,omitempty"`
-	TotalCost       *float64   `json:"total_cost,omitempty"`
-	Finished        *bool      `json:"finished,omitempty"`
-	Discount        *float64   `json:"discount,omitempty"`
-	TotalDriverCost *float64   `json:"total_driver_cost,omitempty"`
+	StartRent       *time.Time    `json:"start_rent,omitempty"`
+	EndRent         *time.Time    `json:"end_rent,omitempty"`
+	TotalCost       *money.Amount `json:"-" binding:"-"`
+	Discount        *money.Amount `json:"-" binding:"-"`
+	TotalDriverCost *money.Amount `json:"-" binding:"-"`
+	VoucherDiscount *money.Amount `json:"-" binding:"-"`
+	RentalTax       *money.Amount `json:"-" binding:"-"`
+	DriverTax       *money.Amount `json:"-" binding:"-"`
+}
+
+// BookingUnitAssignment pins a physical car unit to a booking at pickup
+type BookingUnitAssignment struct {
+	CarUnitID int `json:"car_unit_id" binding:"required"`
+}
+
+// BookingCancellation is the body of the cancel transition
+type BookingCancellation struct {
+	Reason string `json:"reason" binding:"required,max=255"`
+}
+
+// BookingReturn is the optional body of the return and finish endpoints
+type BookingReturn struct {
+	ReturnedAt *time.Time `json:"returned_at,omitempty"`
+}
+
+// BookingPickup is the optional body of the pickup transition
+type BookingPickup struct {
+	CarUnitID *int `json:"car_unit_id,omitempty"`
 }
 
 func (Booking) TableName() string {
 	return "bookings"
 }
+
+// RecordedExchangeRate is the exchange rate the booking was created with, or nil
+func (b Booking) RecordedExchangeRate() *ExchangeRate {
+	if b.Currency == nil || b.ExchangeRateID == nil || b.ExchangeRate == nil {
+		return nil
+	}
+	return &ExchangeRate{No: *b.ExchangeRateID, Currency: *b.Currency, Rate: *b.ExchangeRate}
+}
@@ -0,0 +1,33 @@
+package models
+
+import "testing"
+
+func TestCanTransitionBooking(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     bool
+	}{
+		{BOOKING_STATUS_PENDING, BOOKING_STATUS_CONFIRMED, true},
+		{BOOKING_STATUS_PENDING, BOOKING_STATUS_CANCELLED, true},
+		{BOOKING_STATUS_PENDING, BOOKING_STATUS_PICKED_UP, false},
+		{BOOKING_STATUS_CONFIRMED, BOOKING_STATUS_PICKED_UP, true},
+		{BOOKING_STATUS_CONFIRMED, BOOKING_STATUS_CANCELLED, true},
+		{BOOKING_STATUS_CONFIRMED, BOOKING_STATUS_PENDING, false},
+		{BOOKING_STATUS_PICKED_UP, BOOKING_STATUS_RETURNED, true},
+		{BOOKING_STATUS_PICKED_UP, BOOKING_STATUS_CANCELLED, false},
+		{BOOKING_STATUS_PICKED_UP, BOOKING_STATUS_CLOSED, false},
+		{BOOKING_STATUS_RETURNED, BOOKING_STATUS_CLOSED, true},
+		{BOOKING_STATUS_RETURNED, BOOKING_STATUS_PICKED_UP, false},
+		{BOOKING_STATUS_CLOSED, BOOKING_STATUS_PENDING, false},
+		{BOOKING_STATUS_CANCELLED, BOOKING_STATUS_PENDING, false},
+		{BOOKING_STATUS_CANCELLED, BOOKING_STATUS_CONFIRMED, false},
+		{BOOKING_STATUS_PENDING, BOOKING_STATUS_PENDING, false},
+		{"unknown", BOOKING_STATUS_CONFIRMED, false},
+		{BOOKING_STATUS_PENDING, "unknown", false},
+	}
+	for _, tt := range tests {
+		if got := CanTransitionBooking(tt.from, tt.to); got != tt.want {
+			t.Errorf("CanTransitionBooking(%q, %q) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
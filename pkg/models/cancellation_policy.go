@@ -0,0 +1,17 @@
+package models
+
+import "car-rental/pkg/money"
+
+// CancellationPolicyTier charges FeePercent of the booking total when a booking is cancelled
+// at least MinHoursBeforeStart hours before its StartRent. The tier with the highest
+// threshold that the cancellation still meets applies.
+type CancellationPolicyTier struct {
+	No                  int        `json:"no" gorm:"primaryKey;column:no;autoIncrement"`
+	MinHoursBeforeStart int        `json:"min_hours_before_start" binding:"min=0" gorm:"column:min_hours_before_start;not null;unique"`
+	FeePercent          money.Rate `json:"fee_percent" binding:"min=0,max=1000000" gorm:"column:fee_percent;not null"` // Capped at 100% (1000000 in rate units)
+	Description         string     `json:"description" gorm:"column:description"`
+}
+
+func (CancellationPolicyTier) TableName() string {
+	return "cancellation_policy_tiers"
+}
@@ -1,15 +1,73 @@
 package models
 
-import "time"
+import (
+	"car-rental/pkg/money"
+	"time"
+)
 
 type Car struct {
-	No        int        `json:"no" gorm:"primaryKey;column:no;autoIncrement"`
-	Name      string     `json:"name" binding:"required" gorm:"column:name;not null"`
-	Stock     int        `json:"stock" binding:"required,min=0" gorm:"column:stock;not null"`
-	DailyRent float64    `json:"daily_rent" binding:"required,min=0" gorm:"column:daily_rent;not null"`
-	DeletedAt *time.Time `json:"deleted_at,omitempty" gorm:"column:deleted_at;index"`
+	No        int          `json:"no" gorm:"primaryKey;column:no;autoIncrement"`
+	Name      string       `json:"name" binding:"required" gorm:"column:name;not null"`
+	Stock     int          `json:"stock" binding:"required,min=0" gorm:"column:stock;not null"` // Fleet size, never changed by bookings
+	DailyRent money.Amount `json:"daily_rent" binding:"required,min=0" gorm:"column:daily_rent;not null"`
+	DeletedAt *time.Time   `json:"deleted_at,omitempty" gorm:"column:deleted_at;index"`
+	Version   int          `json:"version" binding:"-" gorm:"column:version;not null;default:1;<-:create"` // Bumped by the database on every change, sent as the ETag
+
+	// WeekendRent is charged for Fridays, Saturdays and Sundays instead of DailyRent when set
+	WeekendRent *money.Amount `json:"weekend_rent" binding:"omitempty,min=0" gorm:"column:weekend_rent"`
+	// Rental length limits in days, unlimited when not set
+	MinRentalDays *int `json:"min_rental_days" binding:"omitempty,min=1" gorm:"column:min_rental_days"`
+	MaxRentalDays *int `json:"max_rental_days" binding:"omitempty,min=1" gorm:"column:max_rental_days"`
+
+	// Available is derived from overlapping bookings and is only filled by read endpoints
+	Available *int `json:"available,omitempty" gorm:"-" binding:"-"`
+	// Converted is only filled on responses requested with ?currency=
+	Converted *CurrencyConversion `json:"converted,omitempty" gorm:"-" binding:"-"`
 }
 
 func (Car) TableName() string {
 	return "cars"
 }
+
+// CarUpdate is the body of PUT /cars/:id. Optional fields left out keep their stored value;
+// the ones named in Clear are removed, which makes the rent or limit unset again.
+type CarUpdate struct {
+	Car
+	Clear []string `json:"clear" binding:"omitempty,dive,oneof=weekend_rent min_rental_days max_rental_days"`
+}
+
+// Clears reports whether the update removes the given optional field
+func (u CarUpdate) Clears(field string) bool {
+	for _, cleared := range u.Clear {
+		if cleared == field {
+			return true
+		}
+	}
+	return false
+}
+
+// PriceAmounts are the car's prices shown by a currency conversion
+func (c Car) PriceAmounts() map[string]money.Amount {
+	amounts := map[string]money.Amount{"daily_rent": c.DailyRent}
+	if c.WeekendRent != nil {
+		amounts["weekend_rent"] = *c.WeekendRent
+	}
+	return amounts
+}
+
+// IsWeekendDay reports whether a day is charged at the weekend rent
+func IsWeekendDay(day time.Time) bool {
+	switch day.Weekday() {
+	case time.Friday, time.Saturday, time.Sunday:
+		return true
+	}
+	return false
+}
+
+// RentForDay is the car's rent for a day before any rate rule applies
+func (c Car) RentForDay(day time.Time) money.Amount {
+	if c.WeekendRent != nil && IsWeekendDay(day) {
+		return *c.WeekendRent
+	}
+	return c.DailyRent
+}
@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// Car unit status constants
+const (
+	CAR_UNIT_STATUS_AVAILABLE   = "available"
+	CAR_UNIT_STATUS_MAINTENANCE = "maintenance"
+	CAR_UNIT_STATUS_RETIRED     = "retired"
+)
+
+// CarUnit is one physical vehicle of a Car model
+type CarUnit struct {
+	No          int        `json:"no" gorm:"primaryKey;column:no;autoIncrement"`
+	CarID       int        `json:"car_id" binding:"-" gorm:"column:car_id;not null;index"`
+	PlateNumber string     `json:"plate_number" binding:"required,max=12" gorm:"column:plate_number;not null;unique;size:12"`
+	VIN         string     `json:"vin" binding:"required,len=17" gorm:"column:vin;not null;unique;size:17"`
+	Colour      string     `json:"colour" binding:"required" gorm:"column:colour;not null"`
+	Year        int        `json:"year" binding:"required,min=1900" gorm:"column:year;not null"`
+	Status      string     `json:"status" binding:"omitempty,oneof=available maintenance retired" gorm:"column:status;not null;default:available"`
+	DeletedAt   *time.Time `json:"deleted_at,omitempty" gorm:"column:deleted_at;index"`
+}
+
+type CarUnitUpdate struct {
+	PlateNumber *string `json:"plate_number,omitempty" binding:"omitempty,max=12"`
+	VIN         *string `json:"vin,omitempty" binding:"omitempty,len=17"`
+	Colour      *string `json:"colour,omitempty"`
+	Year        *int    `json:"year,omitempty" binding:"omitempty,min=1900"`
+	Status      *string `json:"status,omitempty" binding:"omitempty,oneof=available maintenance retired"`
+}
+
+func (CarUnit) TableName() string {
+	return "car_units"
+}
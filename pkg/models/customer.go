@@ -8,6 +8,7 @@ type Customer struct {
 	NIK         string     `json:"nik" binding:"required,len=16" gorm:"column:nik;not null;unique;size:16"`
 	PhoneNumber string     `json:"phone_number" binding:"required,max=15" gorm:"column:phone_number;not null;size:15"`
 	DeletedAt   *time.Time `json:"deleted_at,omitempty" gorm:"column:deleted_at;index"`
+	Version     int        `json:"version" binding:"-" gorm:"column:version;not null;default:1;<-:create"` // Row version behind the ETag
 
 	MembershipID *int        `json:"membership_id" gorm:"column:membership_id"`
 	Membership   *Membership `json:"membership,omitempty" gorm:"foreignKey:MembershipID;references:No"`
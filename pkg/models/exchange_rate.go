@@ -0,0 +1,46 @@
+package models
+
+import (
+	"car-rental/pkg/money"
+	"time"
+)
+
+// BASE_CURRENCY is the currency every price is stored and charged in
+const BASE_CURRENCY = "IDR"
+
+// ExchangeRate is the rupiah price of one unit of a foreign currency from a point in time.
+// Rates are never edited: a new rate is a new row, so bookings keep pointing at the rate
+// they were quoted at.
+type ExchangeRate struct {
+	No            int        `json:"no" gorm:"primaryKey;column:no;autoIncrement"`
+	Currency      string     `json:"currency" binding:"required,len=3,alpha" gorm:"column:currency;not null;size:3;index"`
+	Rate          money.Rate `json:"rate" binding:"required,gt=0" gorm:"column:rate;not null"`   // Rupiah per unit
+	EffectiveFrom time.Time  `json:"effective_from" gorm:"column:effective_from;not null;index"` // Defaults to the time it is added
+}
+
+func (ExchangeRate) TableName() string {
+	return "exchange_rates"
+}
+
+// CurrencyConversion shows base amounts in another currency. It is only filled on responses
+// requested with ?currency=.
+type CurrencyConversion struct {
+	Currency       string                  `json:"currency"`
+	ExchangeRateID int                     `json:"exchange_rate_id"`
+	ExchangeRate   money.Rate              `json:"exchange_rate"`
+	Amounts        map[string]money.Amount `json:"amounts"`
+}
+
+// Convert shows amounts, keyed by the name of the field they come from, in the rate's currency
+func (r ExchangeRate) Convert(amounts map[string]money.Amount) *CurrencyConversion {
+	conversion := &CurrencyConversion{
+		Currency:       r.Currency,
+		ExchangeRateID: r.No,
+		ExchangeRate:   r.Rate,
+		Amounts:        make(map[string]money.Amount, len(amounts)),
+	}
+	for name, amount := range amounts {
+		conversion.Amounts[name] = amount.Exchange(r.Rate)
+	}
+	return conversion
+}
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"car-rental/pkg/money"
+	"time"
+)
+
+// GatewayCharge is an online payment started through a payment gateway. The ledger entry is
+// only written once the provider's webhook reports the charge as succeeded.
+type GatewayCharge struct {
+	No             int          `json:"no" gorm:"primaryKey;column:no;autoIncrement"`
+	BookingID      int          `json:"booking_id" gorm:"column:booking_id;not null;index"`
+	Provider       string       `json:"provider" gorm:"column:provider;not null;size:30"`
+	ChargeID       string       `json:"charge_id" gorm:"column:charge_id;not null;unique"`
+	PaymentType    string       `json:"payment_type" gorm:"column:payment_type;not null"`
+	Amount         money.Amount `json:"amount" gorm:"column:amount;not null"`
+	Status         string       `json:"status" gorm:"column:status;not null;index"`
+	CheckoutURL    string       `json:"checkout_url" gorm:"column:checkout_url"`
+	PaymentID      *int         `json:"payment_id" gorm:"column:payment_id"`
+	RefundedAmount money.Amount `json:"refunded_amount" gorm:"column:refunded_amount;not null;default:0"`
+	CreatedAt      *time.Time   `json:"created_at,omitempty" gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt      *time.Time   `json:"updated_at,omitempty" gorm:"column:updated_at;autoUpdateTime"`
+}
+
+func (GatewayCharge) TableName() string {
+	return "gateway_charges"
+}
+
+// GatewayRefund is a refund sent through a payment gateway. It is recorded as pending before the
+// provider is called, so its amount stays reserved while the call is in flight, and settled with
+// the provider's answer.
+type GatewayRefund struct {
+	No        int          `json:"no" gorm:"primaryKey;column:no;autoIncrement"`
+	ChargeNo  int          `json:"charge_no" gorm:"column:charge_no;not null;index"`
+	BookingID int          `json:"booking_id" gorm:"column:booking_id;not null;index"`
+	Amount    money.Amount `json:"amount" gorm:"column:amount;not null"`
+	Reason    string       `json:"reason" gorm:"column:reason;not null"`
+	Status    string       `json:"status" gorm:"column:status;not null;index"`
+	RefundID  string       `json:"refund_id" gorm:"column:refund_id"`
+	PaymentID *int         `json:"payment_id" gorm:"column:payment_id"`
+	CreatedAt *time.Time   `json:"created_at,omitempty" gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt *time.Time   `json:"updated_at,omitempty" gorm:"column:updated_at;autoUpdateTime"`
+}
+
+func (GatewayRefund) TableName() string {
+	return "gateway_refunds"
+}
+
+// GatewayChargeRequest is the body of POST /bookings/:id/charges
+type GatewayChargeRequest struct {
+	Provider string       `json:"provider" binding:"max=30"`
+	Type     string       `json:"type" binding:"required,oneof=deposit installment settlement"`
+	Amount   money.Amount `json:"amount" binding:"required,gt=0"`
+}
+
+// GatewayRefundRequest is the body of POST /bookings/:id/charges/:charge_id/refund
+type GatewayRefundRequest struct {
+	Amount money.Amount `json:"amount" binding:"required,gt=0"`
+	Reason string       `json:"reason" binding:"required,max=255"`
+}
+
+// FakeChargeCompletion is the body of the fake provider's checkout simulation
+type FakeChargeCompletion struct {
+	Outcome string `json:"outcome" binding:"required,oneof=succeeded failed"`
+}
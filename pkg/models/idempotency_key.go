@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// IdempotencyKey remembers a request sent with an Idempotency-Key header so a retry gets the
+// first response back instead of running again. Keys are scoped to the caller that sent
+// them. CompletedAt is null while the first request is still running, and HeartbeatAt is
+// refreshed while it runs so a request that died can be told from a slow one.
+type IdempotencyKey struct {
+	No              int        `json:"no" gorm:"primaryKey;column:no;autoIncrement"`
+	Scope           string     `json:"scope" gorm:"column:scope;not null;size:100;uniqueIndex:idx_idempotency_scope_key"` // API key, user or IP of the caller
+	Key             string     `json:"key" gorm:"column:key;not null;size:255;uniqueIndex:idx_idempotency_scope_key"`
+	Fingerprint     string     `json:"fingerprint" gorm:"column:fingerprint;not null;size:64"` // SHA-256 of the method, URL and body
+	StatusCode      int        `json:"status_code" gorm:"column:status_code;not null;default:0"`
+	ContentType     string     `json:"content_type" gorm:"column:content_type"`
+	ResponseBody    []byte     `json:"-" gorm:"column:response_body"`
+	ResponseHeaders string     `json:"-" gorm:"column:response_headers;type:text"` // JSON of the headers the handler set, e.g. ETag and Location
+	CreatedAt       time.Time  `json:"created_at" gorm:"column:created_at;not null"`
+	HeartbeatAt     time.Time  `json:"heartbeat_at" gorm:"column:heartbeat_at;not null;default:CURRENT_TIMESTAMP"`
+	CompletedAt     *time.Time `json:"completed_at" gorm:"column:completed_at"`
+	ExpiresAt       time.Time  `json:"expires_at" gorm:"column:expires_at;not null;index"`
+}
+
+func (IdempotencyKey) TableName() string {
+	return "idempotency_keys"
+}
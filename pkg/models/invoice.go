@@ -0,0 +1,67 @@
+package models
+
+import (
+	"car-rental/pkg/money"
+	"time"
+)
+
+// Invoice format constants, selected with ?format= on GET /bookings/:id/invoice
+const (
+	INVOICE_FORMAT_JSON = "json"
+	INVOICE_FORMAT_HTML = "html"
+	INVOICE_FORMAT_PDF  = "pdf"
+)
+
+// Invoice is issued once when a booking is returned. Customer and car details are copied
+// so the invoice keeps reading the same after either record changes.
+type Invoice struct {
+	No            int          `json:"no" gorm:"primaryKey;column:no;autoIncrement"`
+	Number        string       `json:"number" gorm:"column:number;not null;unique;size:20"` // INV-<year>-<sequence>
+	BookingID     int          `json:"booking_id" gorm:"column:booking_id;not null;unique"`
+	IssuedAt      time.Time    `json:"issued_at" gorm:"column:issued_at;not null"`
+	CustomerName  string       `json:"customer_name" gorm:"column:customer_name;not null"`
+	CustomerNIK   string       `json:"customer_nik" gorm:"column:customer_nik;not null;size:16"`
+	CarName       string       `json:"car_name" gorm:"column:car_name;not null"`
+	StartRent     time.Time    `json:"start_rent" gorm:"column:start_rent;not null"`
+	EndRent       time.Time    `json:"end_rent" gorm:"column:end_rent;not null"`
+	ReturnedAt    *time.Time   `json:"returned_at,omitempty" gorm:"column:returned_at"`
+	Subtotal      money.Amount `json:"subtotal" gorm:"column:subtotal;not null"`             // Rent, driver cost and late fees
+	DiscountTotal money.Amount `json:"discount_total" gorm:"column:discount_total;not null"` // Membership and voucher discounts
+	TaxTotal      money.Amount `json:"tax_total" gorm:"column:tax_total;not null;default:0"`
+	Total         money.Amount `json:"total" gorm:"column:total;not null"`
+
+	// Total in the currency the booking was quoted in, at the rate recorded on the booking
+	Currency       *string       `json:"currency,omitempty" gorm:"column:currency;size:3"`
+	ExchangeRate   *money.Rate   `json:"exchange_rate,omitempty" gorm:"column:exchange_rate"`
+	ConvertedTotal *money.Amount `json:"converted_total,omitempty" gorm:"column:converted_total"`
+
+	Lines []InvoiceLine `json:"lines" gorm:"foreignKey:InvoiceID;references:No"`
+}
+
+func (Invoice) TableName() string {
+	return "invoices"
+}
+
+// InvoiceLine is one charge or discount on an invoice. Discounts have a negative amount.
+type InvoiceLine struct {
+	No          int          `json:"no" gorm:"primaryKey;column:no;autoIncrement"`
+	InvoiceID   int          `json:"invoice_id" gorm:"column:invoice_id;not null;index"`
+	Position    int          `json:"position" gorm:"column:position;not null"`
+	Description string       `json:"description" gorm:"column:description;not null"`
+	Amount      money.Amount `json:"amount" gorm:"column:amount;not null"`
+}
+
+func (InvoiceLine) TableName() string {
+	return "invoice_lines"
+}
+
+// InvoiceSequence holds the last invoice number handed out in a year. Numbers are taken
+// inside the issuing transaction, so they stay gapless.
+type InvoiceSequence struct {
+	Year       int `json:"year" gorm:"primaryKey;column:year;autoIncrement:false"`
+	LastNumber int `json:"last_number" gorm:"column:last_number;not null"`
+}
+
+func (InvoiceSequence) TableName() string {
+	return "invoice_sequences"
+}
@@ -0,0 +1,104 @@
+package models
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// API key scope constants
+const (
+	SCOPE_CARS_READ       = "cars:read"       // List cars and check availability
+	SCOPE_BOOKINGS_CREATE = "bookings:create" // Quote and create bookings
+	SCOPE_BOOKINGS_READ   = "bookings:read"   // Read the bookings the partner created
+)
+
+// APIKeyScopes lists every scope an API key can have
+var APIKeyScopes = []string{SCOPE_CARS_READ, SCOPE_BOOKINGS_CREATE, SCOPE_BOOKINGS_READ}
+
+// Partner is a hotel, travel agent or other business booking through the API with API keys.
+// Its bookings are made for, and billed to, the partner's customer record.
+type Partner struct {
+	No           int        `json:"no" gorm:"primaryKey;column:no;autoIncrement"`
+	Name         string     `json:"name" binding:"required,max=100" gorm:"column:name;not null;size:100"`
+	ContactEmail string     `json:"contact_email" binding:"required,email" gorm:"column:contact_email;not null"`
+	CustomerID   int        `json:"customer_id" binding:"required" gorm:"column:customer_id;not null;index"`
+	CreatedAt    *time.Time `json:"created_at,omitempty" binding:"-" gorm:"column:created_at;autoCreateTime"`
+
+	Customer *Customer `json:"customer,omitempty" binding:"-" gorm:"foreignKey:CustomerID;references:No"`
+}
+
+func (Partner) TableName() string {
+	return "partners"
+}
+
+// APIKey lets a partner call the partner routes with an X-API-Key header. Only a SHA-256
+// hash of the key is stored; the key itself is shown once, when it is issued.
+type APIKey struct {
+	No           int        `json:"no" gorm:"primaryKey;column:no;autoIncrement"`
+	PartnerID    int        `json:"partner_id" gorm:"column:partner_id;not null;index"`
+	Name         string     `json:"name" gorm:"column:name;not null;size:100"`
+	Prefix       string     `json:"prefix" gorm:"column:prefix;not null;size:12"` // First characters of the key, to tell keys apart
+	KeyHash      string     `json:"-" gorm:"column:key_hash;not null;unique;size:64"`
+	Scopes       ScopeList  `json:"scopes" gorm:"column:scopes;not null"`
+	RequestCount int64      `json:"request_count" gorm:"column:request_count;not null;default:0"`
+	LastUsedAt   *time.Time `json:"last_used_at" gorm:"column:last_used_at"`
+	RevokedAt    *time.Time `json:"revoked_at" gorm:"column:revoked_at"`
+	CreatedAt    *time.Time `json:"created_at,omitempty" gorm:"column:created_at;autoCreateTime"`
+
+	Partner *Partner `json:"partner,omitempty" gorm:"foreignKey:PartnerID;references:No"`
+}
+
+func (APIKey) TableName() string {
+	return "api_keys"
+}
+
+// HasScope reports whether the key was granted a scope
+func (k APIKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// APIKeyRequest is the body of POST /admin/partners/:id/api-keys
+type APIKeyRequest struct {
+	Name   string   `json:"name" binding:"required,max=100"`
+	Scopes []string `json:"scopes" binding:"required,min=1,dive,oneof=cars:read bookings:create bookings:read"`
+}
+
+// IssuedAPIKey is returned once when a key is issued; the plain key cannot be read again
+type IssuedAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+// ScopeList is stored as a space separated text column and written to JSON as an array
+type ScopeList []string
+
+func (s ScopeList) Value() (driver.Value, error) {
+	return strings.Join(s, " "), nil
+}
+
+func (s *ScopeList) Scan(src interface{}) error {
+	var text string
+	switch v := src.(type) {
+	case nil:
+	case []byte:
+		text = string(v)
+	case string:
+		text = v
+	default:
+		return fmt.Errorf("cannot scan %T into a scope list", src)
+	}
+	*s = strings.Fields(text)
+	return nil
+}
+
+// GormDataType makes AutoMigrate store scope lists as text
+func (ScopeList) GormDataType() string {
+	return "text"
+}
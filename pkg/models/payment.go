@@ -0,0 +1,59 @@
+package models
+
+import (
+	"car-rental/pkg/money"
+	"time"
+)
+
+// Payment type constants
+const (
+	PAYMENT_TYPE_DEPOSIT     = "deposit"     // Paid to secure a reservation
+	PAYMENT_TYPE_INSTALLMENT = "installment" // Partial payment towards the balance
+	PAYMENT_TYPE_SETTLEMENT  = "settlement"  // Final payment clearing the balance
+	PAYMENT_TYPE_REFUND      = "refund"      // Money paid back to the customer
+)
+
+// Payment status constants, derived from the ledger of a booking
+const (
+	PAYMENT_STATUS_UNPAID         = "unpaid"
+	PAYMENT_STATUS_PARTIALLY_PAID = "partially_paid"
+	PAYMENT_STATUS_PAID           = "paid"
+	PAYMENT_STATUS_REFUND_DUE     = "refund_due" // More was paid than is owed
+)
+
+// Payment is one entry in the ledger of a booking. Amounts are always positive;
+// refunds are told apart by their Type.
+type Payment struct {
+	No        int          `json:"no" gorm:"primaryKey;column:no;autoIncrement"`
+	BookingID int          `json:"booking_id" gorm:"column:booking_id;not null;index"`
+	Type      string       `json:"type" gorm:"column:type;not null"`
+	Amount    money.Amount `json:"amount" gorm:"column:amount;not null"`
+	Method    string       `json:"method" gorm:"column:method;not null;size:30"`
+	Reference string       `json:"reference,omitempty" gorm:"column:reference"`
+	Note      string       `json:"note,omitempty" gorm:"column:note"`
+	PaidAt    time.Time    `json:"paid_at" gorm:"column:paid_at;not null"`
+	CreatedAt *time.Time   `json:"created_at,omitempty" gorm:"column:created_at;autoCreateTime"`
+}
+
+func (Payment) TableName() string {
+	return "payments"
+}
+
+// PaymentRequest is the body of POST /bookings/:id/payments
+type PaymentRequest struct {
+	Type      string       `json:"type" binding:"required,oneof=deposit installment settlement"`
+	Amount    money.Amount `json:"amount" binding:"required,gt=0"`
+	Method    string       `json:"method" binding:"required,max=30"`
+	Reference string       `json:"reference" binding:"max=100"`
+	Note      string       `json:"note" binding:"max=255"`
+	PaidAt    *time.Time   `json:"paid_at"`
+}
+
+// RefundRequest is the body of POST /bookings/:id/refunds
+type RefundRequest struct {
+	Amount    money.Amount `json:"amount" binding:"required,gt=0"`
+	Method    string       `json:"method" binding:"required,max=30"`
+	Reference string       `json:"reference" binding:"max=100"`
+	Reason    string       `json:"reason" binding:"required,max=255"`
+	PaidAt    *time.Time   `json:"paid_at"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"car-rental/pkg/money"
+	"time"
+)
+
+// RateRule changes the daily rent for every day between StartDate and EndDate (inclusive).
+// It either scales the car's daily rent by Multiplier or replaces it with FixedRate.
+// Rules without a CarID apply to every car. When several rules cover the same day the one
+// with the highest Priority wins, then a car-specific rule over a general one.
+type RateRule struct {
+	No         int           `json:"no" gorm:"primaryKey;column:no;autoIncrement"`
+	Name       string        `json:"name" binding:"required,max=100" gorm:"column:name;not null;size:100"`
+	StartDate  time.Time     `json:"start_date" binding:"required" gorm:"column:start_date;type:date;not null;index"`
+	EndDate    time.Time     `json:"end_date" binding:"required" gorm:"column:end_date;type:date;not null;index"`
+	CarID      *int          `json:"car_id" gorm:"column:car_id;index"`
+	Multiplier *money.Rate   `json:"multiplier" binding:"omitempty,gt=0" gorm:"column:multiplier"`
+	FixedRate  *money.Amount `json:"fixed_rate" binding:"omitempty,gte=0" gorm:"column:fixed_rate"`
+	Priority   int           `json:"priority" gorm:"column:priority;not null;default:0"`
+	Car        *Car          `json:"car,omitempty" binding:"-" gorm:"foreignKey:CarID;references:No"`
+}
+
+func (RateRule) TableName() string {
+	return "rate_rules"
+}
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// SchemaMigration records one-off data migrations that have already been applied
+type SchemaMigration struct {
+	Name      string    `json:"name" gorm:"primaryKey;column:name"`
+	AppliedAt time.Time `json:"applied_at" gorm:"column:applied_at;not null"`
+}
+
+func (SchemaMigration) TableName() string {
+	return "schema_migrations"
+}
@@ -0,0 +1,21 @@
+package models
+
+import "car-rental/pkg/money"
+
+// TaxRate is a tax charged as a percentage of the rental, driver and fee amounts it applies
+// to. Rates are charged after the membership and voucher discounts unless
+// AppliedBeforeDiscount is set; discounts only reduce the rent, so the flag only matters
+// for rental tax. Every matching rate is charged, so rates add up.
+type TaxRate struct {
+	No                    int        `json:"no" gorm:"primaryKey;column:no;autoIncrement"`
+	Name                  string     `json:"name" binding:"required,max=50" gorm:"column:name;not null;size:50"`
+	Rate                  money.Rate `json:"rate" binding:"required,gt=0,lte=1000000" gorm:"column:rate;not null"` // Percent, capped at 100% (1000000 in rate units)
+	AppliesToRental       bool       `json:"applies_to_rental" gorm:"column:applies_to_rental;not null"`
+	AppliesToDriver       bool       `json:"applies_to_driver" gorm:"column:applies_to_driver;not null"`
+	AppliesToFees         bool       `json:"applies_to_fees" gorm:"column:applies_to_fees;not null"` // Late return and cancellation fees
+	AppliedBeforeDiscount bool       `json:"applied_before_discount" gorm:"column:applied_before_discount;not null"`
+}
+
+func (TaxRate) TableName() string {
+	return "tax_rates"
+}
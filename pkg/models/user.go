@@ -0,0 +1,91 @@
+package models
+
+import "time"
+
+// User role constants
+const (
+	ROLE_ADMIN    = "admin"    // Everything, including cars, prices and users
+	ROLE_STAFF    = "staff"    // Branch staff running customers, bookings and payments
+	ROLE_DRIVER   = "driver"   // Sees the bookings and incentives of one driver
+	ROLE_CUSTOMER = "customer" // Sees the bookings of one customer
+)
+
+// UserRoles lists every role a user can have
+var UserRoles = []string{ROLE_ADMIN, ROLE_STAFF, ROLE_DRIVER, ROLE_CUSTOMER}
+
+// User is an account that signs in to the v2 API. Only the bcrypt hash of the password is
+// stored. Driver and customer users are linked to the record whose data they may see.
+type User struct {
+	No           int        `json:"no" gorm:"primaryKey;column:no;autoIncrement"`
+	Username     string     `json:"username" gorm:"column:username;not null;unique;size:50"`
+	Name         string     `json:"name" gorm:"column:name;not null"`
+	PasswordHash string     `json:"-" gorm:"column:password_hash;not null"`
+	Role         string     `json:"role" gorm:"column:role;not null;default:staff;size:20"`
+	DriverID     *int       `json:"driver_id" gorm:"column:driver_id;index"`     // Set for the driver role
+	CustomerID   *int       `json:"customer_id" gorm:"column:customer_id;index"` // Set for the customer role
+	Active       bool       `json:"active" gorm:"column:active;not null;default:true"`
+	LastLoginAt  *time.Time `json:"last_login_at,omitempty" gorm:"column:last_login_at"`
+	CreatedAt    *time.Time `json:"created_at,omitempty" gorm:"column:created_at;autoCreateTime"`
+}
+
+func (User) TableName() string {
+	return "users"
+}
+
+// UserRequest is the body of POST /users
+type UserRequest struct {
+	Username   string `json:"username" binding:"required,min=3,max=50"`
+	Name       string `json:"name" binding:"required"`
+	Password   string `json:"password" binding:"required,min=8,max=72"` // bcrypt reads at most 72 bytes
+	Role       string `json:"role" binding:"required,oneof=admin staff driver customer"`
+	DriverID   *int   `json:"driver_id"`
+	CustomerID *int   `json:"customer_id"`
+}
+
+// UserUpdate is the body of PUT /users/:id. A new password or role signs the user out
+// everywhere. Changing the role replaces driver_id and customer_id with the ones sent.
+type UserUpdate struct {
+	Name       *string `json:"name,omitempty" binding:"omitempty,min=1"`
+	Password   *string `json:"password,omitempty" binding:"omitempty,min=8,max=72"`
+	Role       *string `json:"role,omitempty" binding:"omitempty,oneof=admin staff driver customer"`
+	DriverID   *int    `json:"driver_id,omitempty"`
+	CustomerID *int    `json:"customer_id,omitempty"`
+	Active     *bool   `json:"active,omitempty"`
+}
+
+// RefreshToken is a long-lived token traded for a new access token. Each one is used once:
+// refreshing revokes it and issues its replacement. Only a SHA-256 hash is stored.
+type RefreshToken struct {
+	No           int        `json:"no" gorm:"primaryKey;column:no;autoIncrement"`
+	UserID       int        `json:"user_id" gorm:"column:user_id;not null;index"`
+	TokenHash    string     `json:"-" gorm:"column:token_hash;not null;unique;size:64"`
+	ExpiresAt    time.Time  `json:"expires_at" gorm:"column:expires_at;not null"`
+	RevokedAt    *time.Time `json:"revoked_at,omitempty" gorm:"column:revoked_at"`
+	ReplacedByID *int       `json:"replaced_by_id,omitempty" gorm:"column:replaced_by_id"`
+	CreatedAt    *time.Time `json:"created_at,omitempty" gorm:"column:created_at;autoCreateTime"`
+}
+
+func (RefreshToken) TableName() string {
+	return "refresh_tokens"
+}
+
+// LoginRequest is the body of POST /auth/login
+type LoginRequest struct {
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// SignupRequest is the body of POST /auth/signup. It creates a customer and a customer user
+// for them.
+type SignupRequest struct {
+	Username    string `json:"username" binding:"required,min=3,max=50"`
+	Password    string `json:"password" binding:"required,min=8,max=72"`
+	Name        string `json:"name" binding:"required"`
+	NIK         string `json:"nik" binding:"required,len=16"`
+	PhoneNumber string `json:"phone_number" binding:"required,max=15"`
+}
+
+// RefreshRequest is the body of POST /auth/refresh and POST /auth/logout
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"car-rental/pkg/money"
+	"time"
+)
+
+// Voucher discount type constants
+const (
+	VOUCHER_TYPE_PERCENT = "percent" // Percent of the rent is taken off
+	VOUCHER_TYPE_FIXED   = "fixed"   // Amount is taken off the rent
+)
+
+// Voucher is a promo code customers enter when booking. It only discounts the rent.
+// With StackWithMembership the voucher applies on top of the membership discount,
+// otherwise it replaces the membership discount and is only accepted when it is worth more.
+type Voucher struct {
+	No                  int          `json:"no" gorm:"primaryKey;column:no;autoIncrement"`
+	Code                string       `json:"code" binding:"required,max=32" gorm:"column:code;not null;unique;size:32"`
+	Description         string       `json:"description" gorm:"column:description"`
+	DiscountType        string       `json:"discount_type" binding:"required,oneof=percent fixed" gorm:"column:discount_type;not null"`
+	Amount              money.Amount `json:"amount" binding:"omitempty,gt=0" gorm:"column:amount;not null;default:0"`               // Fixed vouchers only
+	Percent             money.Rate   `json:"percent" binding:"omitempty,gt=0,lte=1000000" gorm:"column:percent;not null;default:0"` // Percent vouchers only, capped at 100% (1000000 in rate units)
+	ValidFrom           time.Time    `json:"valid_from" binding:"required" gorm:"column:valid_from;not null"`
+	ValidUntil          time.Time    `json:"valid_until" binding:"required" gorm:"column:valid_until;not null"`
+	UsageLimit          *int         `json:"usage_limit" binding:"omitempty,min=1" gorm:"column:usage_limit"`
+	PerCustomerLimit    *int         `json:"per_customer_limit" binding:"omitempty,min=1" gorm:"column:per_customer_limit"`
+	MinRentalDays       int          `json:"min_rental_days" binding:"min=0" gorm:"column:min_rental_days;not null;default:0"`
+	StackWithMembership bool         `json:"stack_with_membership" gorm:"column:stack_with_membership;not null;default:false"`
+	DeletedAt           *time.Time   `json:"deleted_at,omitempty" gorm:"column:deleted_at;index"`
+
+	// CarIDs restricts the voucher to these cars; empty means every car
+	CarIDs []int `json:"car_ids" gorm:"-"`
+	Cars   []Car `json:"cars,omitempty" binding:"-" gorm:"many2many:voucher_cars;joinForeignKey:VoucherID;joinReferences:CarID"`
+}
+
+func (Voucher) TableName() string {
+	return "vouchers"
+}
+
+// AppliesToCar reports whether the voucher may be used for a car
+func (v Voucher) AppliesToCar(carID int) bool {
+	if len(v.Cars) == 0 {
+		return true
+	}
+	for _, car := range v.Cars {
+		if car.No == carID {
+			return true
+		}
+	}
+	return false
+}
+
+// VoucherRedemption records a voucher used on a booking. Cancelling or deleting the booking
+// releases the redemption so it no longer counts towards the usage limits.
+type VoucherRedemption struct {
+	No         int          `json:"no" gorm:"primaryKey;column:no;autoIncrement"`
+	VoucherID  int          `json:"voucher_id" gorm:"column:voucher_id;not null;index"`
+	BookingID  int          `json:"booking_id" gorm:"column:booking_id;not null;unique"`
+	CustomerID int          `json:"customer_id" gorm:"column:customer_id;not null;index"`
+	Discount   money.Amount `json:"discount" gorm:"column:discount;not null"`
+	RedeemedAt time.Time    `json:"redeemed_at" gorm:"column:redeemed_at;not null"`
+	ReleasedAt *time.Time   `json:"released_at,omitempty" gorm:"column:released_at"`
+}
+
+func (VoucherRedemption) TableName() string {
+	return "voucher_redemptions"
+}
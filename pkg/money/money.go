@@ -0,0 +1,249 @@
+// Package money holds exact rupiah amounts and the percentages and multipliers applied to them.
+//
+// Amounts are whole sen (1/100 rupiah) and rates are fixed-point with four decimals, both
+// stored as int64 so sums never drift. Every multiplication rounds once, half away from
+// zero, to the nearest sen. Both types are written to JSON as plain numbers, the same
+// shape the float64 fields they replace had, and stored in Postgres as numeric columns.
+package money
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Fixed-point scales
+const (
+	AMOUNT_SCALE = 100   // Sen per rupiah
+	RATE_SCALE   = 10000 // Four decimal places
+)
+
+// Column types used for both types
+const (
+	AMOUNT_COLUMN_TYPE = "numeric(18,2)"
+	RATE_COLUMN_TYPE   = "numeric(12,4)"
+)
+
+// Amount is an amount of money in sen
+type Amount int64
+
+// Rate is a percentage or multiplier with four decimal places, e.g. 11% is Rate(110000)
+// and a 1.5× multiplier is Rate(15000)
+type Rate int64
+
+// Rupiah returns an amount of whole rupiah
+func Rupiah(rupiah int64) Amount {
+	return Amount(rupiah * AMOUNT_SCALE)
+}
+
+// RateOf returns a whole-number rate, e.g. RateOf(11) for 11%
+func RateOf(n int64) Rate {
+	return Rate(n * RATE_SCALE)
+}
+
+// ParseAmount reads a decimal such as "150000" or "12500.50", rounding past the sen
+func ParseAmount(s string) (Amount, error) {
+	value, err := parseScaled(s, AMOUNT_SCALE)
+	return Amount(value), err
+}
+
+// ParseRate reads a decimal such as "11" or "1.5", rounding past four decimals
+func ParseRate(s string) (Rate, error) {
+	value, err := parseScaled(s, RATE_SCALE)
+	return Rate(value), err
+}
+
+// RateFromFloat converts a float setting such as an environment variable into a rate
+func RateFromFloat(f float64) Rate {
+	rate, _ := ParseRate(strconv.FormatFloat(f, 'f', -1, 64))
+	return rate
+}
+
+// Times multiplies an amount by a whole number, e.g. a daily rent by the rental days
+func (a Amount) Times(n int) Amount {
+	return a * Amount(n)
+}
+
+// Scale multiplies an amount by a multiplier rate
+func (a Amount) Scale(rate Rate) Amount {
+	return a.Fraction(int64(rate), RATE_SCALE)
+}
+
+// Percent takes a percentage of an amount
+func (a Amount) Percent(rate Rate) Amount {
+	return a.Fraction(int64(rate), 100*RATE_SCALE)
+}
+
+// Fraction multiplies an amount by num/den, e.g. one hour of a daily rent is Fraction(1, 24)
+func (a Amount) Fraction(num, den int64) Amount {
+	product := new(big.Rat).SetFrac(new(big.Int).Mul(big.NewInt(int64(a)), big.NewInt(num)), big.NewInt(den))
+	return Amount(roundRat(product))
+}
+
+// Exchange converts a rupiah amount at a rate of rupiah per unit of another currency. The
+// result is in hundredths of that currency.
+func (a Amount) Exchange(rupiahPerUnit Rate) Amount {
+	return a.Fraction(RATE_SCALE, int64(rupiahPerUnit))
+}
+
+// String writes the amount with two decimals, e.g. "150000.50"
+func (a Amount) String() string {
+	return formatScaled(int64(a), AMOUNT_SCALE, false)
+}
+
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return []byte(formatScaled(int64(a), AMOUNT_SCALE, true)), nil
+}
+
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	value, err := unmarshalScaled(data, AMOUNT_SCALE)
+	if err != nil {
+		return fmt.Errorf("invalid amount %s: %w", data, err)
+	}
+	if value != nil {
+		*a = Amount(*value)
+	}
+	return nil
+}
+
+func (a Amount) Value() (driver.Value, error) {
+	return a.String(), nil
+}
+
+func (a *Amount) Scan(src interface{}) error {
+	value, err := scanScaled(src, AMOUNT_SCALE)
+	*a = Amount(value)
+	return err
+}
+
+// GormDataType makes AutoMigrate store amounts as exact numerics
+func (Amount) GormDataType() string {
+	return AMOUNT_COLUMN_TYPE
+}
+
+// String writes the rate without trailing zeros, e.g. "11" or "1.5"
+func (r Rate) String() string {
+	return formatScaled(int64(r), RATE_SCALE, true)
+}
+
+func (r Rate) MarshalJSON() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+func (r *Rate) UnmarshalJSON(data []byte) error {
+	value, err := unmarshalScaled(data, RATE_SCALE)
+	if err != nil {
+		return fmt.Errorf("invalid rate %s: %w", data, err)
+	}
+	if value != nil {
+		*r = Rate(*value)
+	}
+	return nil
+}
+
+func (r Rate) Value() (driver.Value, error) {
+	return formatScaled(int64(r), RATE_SCALE, false), nil
+}
+
+func (r *Rate) Scan(src interface{}) error {
+	value, err := scanScaled(src, RATE_SCALE)
+	*r = Rate(value)
+	return err
+}
+
+// GormDataType makes AutoMigrate store rates as exact numerics
+func (Rate) GormDataType() string {
+	return RATE_COLUMN_TYPE
+}
+
+// Min returns the smaller amount
+func Min(a, b Amount) Amount {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// parseScaled reads a decimal exactly and returns it in units of 1/scale
+func parseScaled(s string, scale int64) (int64, error) {
+	value, ok := new(big.Rat).SetString(strings.TrimSpace(s))
+	if !ok {
+		return 0, fmt.Errorf("%q is not a number", s)
+	}
+	value.Mul(value, new(big.Rat).SetInt64(scale))
+	if !value.IsInt() {
+		return roundRat(value), nil
+	}
+	if !value.Num().IsInt64() {
+		return 0, fmt.Errorf("%q is out of range", s)
+	}
+	return value.Num().Int64(), nil
+}
+
+// roundRat rounds half away from zero
+func roundRat(value *big.Rat) int64 {
+	num := new(big.Int).Abs(value.Num())
+	den := value.Denom()
+
+	// (2|num| + den) / 2den is |value| rounded half up
+	num.Mul(num, big.NewInt(2)).Add(num, den)
+	rounded := num.Quo(num, new(big.Int).Mul(den, big.NewInt(2))).Int64()
+	if value.Sign() < 0 {
+		return -rounded
+	}
+	return rounded
+}
+
+// formatScaled writes a fixed-point value as a decimal. Trimmed output drops trailing
+// zeros and a bare decimal point, as encoding/json does for floats.
+func formatScaled(value, scale int64, trim bool) string {
+	sign := ""
+	if value < 0 {
+		sign = "-"
+		value = -value
+	}
+
+	digits := len(strconv.FormatInt(scale, 10)) - 1
+	s := fmt.Sprintf("%s%d.%0*d", sign, value/scale, digits, value%scale)
+	if trim {
+		s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	}
+	return s
+}
+
+// unmarshalScaled reads a JSON number, or a number in a string. It returns nil for null.
+func unmarshalScaled(data []byte, scale int64) (*int64, error) {
+	s := strings.TrimSpace(string(data))
+	if s == "null" {
+		return nil, nil
+	}
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		s = unquoted
+	}
+
+	value, err := parseScaled(s, scale)
+	if err != nil {
+		return nil, err
+	}
+	return &value, nil
+}
+
+// scanScaled reads a numeric column. Float and integer columns are read too, so rows can
+// be loaded before the column types are migrated.
+func scanScaled(src interface{}, scale int64) (int64, error) {
+	switch v := src.(type) {
+	case nil:
+		return 0, nil
+	case []byte:
+		return parseScaled(string(v), scale)
+	case string:
+		return parseScaled(v, scale)
+	case int64:
+		return v * scale, nil
+	case float64:
+		return parseScaled(strconv.FormatFloat(v, 'f', -1, 64), scale)
+	}
+	return 0, fmt.Errorf("cannot scan %T into a money value", src)
+}
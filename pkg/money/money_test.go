@@ -0,0 +1,218 @@
+package money
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseAmountRoundsHalfAwayFromZero(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Amount
+	}{
+		{"150000", Rupiah(150000)},
+		{"12500.50", 1250050},
+		{"0.004", 0},
+		{"0.005", 1},
+		{"0.015", 2},
+		{"-0.004", 0},
+		{"-0.005", -1},
+		{"-12.345", -1235},
+		{" 7 ", Rupiah(7)},
+	}
+	for _, tt := range tests {
+		got, err := ParseAmount(tt.in)
+		if err != nil {
+			t.Errorf("ParseAmount(%q) error: %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseAmount(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+
+	for _, in := range []string{"", "abc", "1e400"} {
+		if _, err := ParseAmount(in); err == nil {
+			t.Errorf("ParseAmount(%q) succeeded, want an error", in)
+		}
+	}
+}
+
+func TestParseRateRoundsToFourDecimals(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Rate
+	}{
+		{"11", RateOf(11)},
+		{"1.5", 15000},
+		{"0.00005", 1},
+		{"0.00004", 0},
+		{"-0.00005", -1},
+	}
+	for _, tt := range tests {
+		got, err := ParseRate(tt.in)
+		if err != nil {
+			t.Errorf("ParseRate(%q) error: %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseRate(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestMultiplicationRoundsOnceHalfAwayFromZero(t *testing.T) {
+	tests := []struct {
+		name string
+		got  Amount
+		want Amount
+	}{
+		{"half a sen rounds up", Amount(1).Fraction(1, 2), 1},
+		{"just under half a sen rounds down", Amount(49).Fraction(1, 100), 0},
+		{"negative half a sen rounds down", Amount(-1).Fraction(1, 2), -1},
+		{"11% tax of 150000.05", Amount(15000005).Percent(RateOf(11)), 1650001},
+		{"12.5% of 0.20", Amount(20).Percent(Rate(125000)), 3},
+		{"negative 50% of 0.05", Amount(-5).Percent(RateOf(50)), -3},
+		{"1.5x multiplier", Rupiah(100001).Scale(15000), 15000150},
+		{"1.5x multiplier of a negative amount", Amount(-3).Scale(15000), -5},
+		{"one hour of a daily rent", Rupiah(100000).Fraction(1, 24), 416667},
+		{"whole days", Rupiah(250000).Times(3), Rupiah(750000)},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %d, want %d", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestNegativeAmounts(t *testing.T) {
+	refund := Rupiah(-12500) - 50
+
+	if got := refund.String(); got != "-12500.50" {
+		t.Errorf("String() = %q, want -12500.50", got)
+	}
+	if got, _ := json.Marshal(refund); string(got) != "-12500.5" {
+		t.Errorf("MarshalJSON = %s, want -12500.5", got)
+	}
+	if got := Min(refund, 0); got != refund {
+		t.Errorf("Min(%d, 0) = %d, want %d", refund, got, refund)
+	}
+	if got := refund.Percent(RateOf(100)); got != refund {
+		t.Errorf("100%% of %d = %d, want the same amount", refund, got)
+	}
+	if got := Rate(-15000).String(); got != "-1.5" {
+		t.Errorf("Rate(-15000).String() = %q, want -1.5", got)
+	}
+}
+
+func TestExchange(t *testing.T) {
+	tests := []struct {
+		name          string
+		amount        Amount
+		rupiahPerUnit Rate
+		want          Amount
+	}{
+		{"whole units", Rupiah(16000), RateOf(16000), 100},
+		{"fractional rate", Rupiah(100000), 157505000, 635},
+		{"half a cent rounds up", 1, RateOf(2), 1},
+		{"refunds convert to negative amounts", Rupiah(-16000), RateOf(16000), -100},
+	}
+	for _, tt := range tests {
+		if got := tt.amount.Exchange(tt.rupiahPerUnit); got != tt.want {
+			t.Errorf("%s: %s at %s = %d, want %d", tt.name, tt.amount, tt.rupiahPerUnit, got, tt.want)
+		}
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	type priced struct {
+		Total      Amount  `json:"total"`
+		Refund     Amount  `json:"refund"`
+		Discount   Rate    `json:"discount"`
+		Multiplier *Rate   `json:"multiplier"`
+		Fixed      *Amount `json:"fixed"`
+	}
+
+	multiplier := Rate(15000)
+	in := priced{Total: 15000050, Refund: -250, Discount: Rate(125000), Multiplier: &multiplier}
+
+	data, err := json.Marshal(in)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	want := `{"total":150000.5,"refund":-2.5,"discount":12.5,"multiplier":1.5,"fixed":null}`
+	if string(data) != want {
+		t.Errorf("marshal = %s, want %s", data, want)
+	}
+
+	var out priced
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if out.Total != in.Total || out.Refund != in.Refund || out.Discount != in.Discount ||
+		out.Multiplier == nil || *out.Multiplier != multiplier || out.Fixed != nil {
+		t.Errorf("round trip = %+v, want %+v", out, in)
+	}
+}
+
+func TestUnmarshalJSONInputs(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Amount
+	}{
+		{`150000`, Rupiah(150000)},
+		{`"12.34"`, 1234},
+		{`0.1`, 10},
+		{`1.005`, 101},
+		{`-0.005`, -1},
+	}
+	for _, tt := range tests {
+		var got Amount
+		if err := json.Unmarshal([]byte(tt.in), &got); err != nil {
+			t.Errorf("unmarshal %s: %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("unmarshal %s = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+
+	// null leaves the value alone, as it does for floats
+	kept := Rupiah(5)
+	if err := json.Unmarshal([]byte(`null`), &kept); err != nil || kept != Rupiah(5) {
+		t.Errorf("unmarshal null = %d, %v; want %d", kept, err, Rupiah(5))
+	}
+
+	var bad Amount
+	if err := json.Unmarshal([]byte(`"abc"`), &bad); err == nil {
+		t.Errorf("unmarshal \"abc\" succeeded, want an error")
+	}
+}
+
+func TestScanReadsNumericAndFloatColumns(t *testing.T) {
+	tests := []struct {
+		src  interface{}
+		want Amount
+	}{
+		{[]byte("150000.50"), 15000050},
+		{"-12.25", -1225},
+		{int64(7), Rupiah(7)},
+		{0.1 + 0.2, 30},
+		{nil, 0},
+	}
+	for _, tt := range tests {
+		var got Amount
+		if err := got.Scan(tt.src); err != nil {
+			t.Errorf("Scan(%v) error: %v", tt.src, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Scan(%v) = %d, want %d", tt.src, got, tt.want)
+		}
+	}
+
+	value, err := Amount(15000050).Value()
+	if err != nil || value != "150000.50" {
+		t.Errorf("Value() = %v, %v; want 150000.50", value, err)
+	}
+}
@@ -0,0 +1,295 @@
+package pricing
+
+import (
+	"car-rental/pkg/models"
+	"car-rental/pkg/money"
+	"car-rental/pkg/utils"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Line item codes
+const (
+	LINE_ITEM_RENT                = "rent"
+	LINE_ITEM_MEMBERSHIP_DISCOUNT = "membership_discount"
+	LINE_ITEM_DRIVER              = "driver"
+	LINE_ITEM_VOUCHER             = "voucher"
+	LINE_ITEM_TAX                 = "tax"
+)
+
+// Input is everything the price of a rental depends on
+type Input struct {
+	Car        models.Car
+	Membership *models.Membership
+	Driver     *models.Driver
+	Voucher    *models.Voucher
+	StartRent  time.Time
+	EndRent    time.Time
+	// RateRules may hold rules for other cars and dates, only the matching ones are used
+	RateRules []models.RateRule
+	TaxRates  []models.TaxRate
+}
+
+// DayRate is the rent charged for one day of the rental
+type DayRate struct {
+	Date       string       `json:"date"`
+	Weekend    bool         `json:"weekend"`
+	DailyRent  money.Amount `json:"daily_rent"`
+	RateRuleID *int         `json:"rate_rule_id"`
+	RateRule   string       `json:"rate_rule,omitempty"`
+}
+
+// LineItem is one row of a price breakdown. Discounts have a negative amount.
+type LineItem struct {
+	Code        string       `json:"code"`
+	Description string       `json:"description"`
+	Quantity    int          `json:"quantity"`
+	UnitPrice   money.Amount `json:"unit_price"`
+	Amount      money.Amount `json:"amount"`
+}
+
+// Quote is the priced rental. TotalCost, Discount and TotalDriverCost map to the booking fields.
+type Quote struct {
+	StartRent       time.Time    `json:"start_rent"`
+	EndRent         time.Time    `json:"end_rent"`
+	RentalDays      int          `json:"rental_days"`
+	TotalCost       money.Amount `json:"total_cost"`
+	DiscountPercent money.Rate   `json:"discount_percent"`
+	Discount        money.Amount `json:"discount"`
+	TotalDriverCost money.Amount `json:"total_driver_cost"`
+	VoucherCode     string       `json:"voucher_code,omitempty"`
+	VoucherDiscount money.Amount `json:"voucher_discount"`
+	RentalTax       money.Amount `json:"rental_tax"`
+	DriverTax       money.Amount `json:"driver_tax"`
+	TaxTotal        money.Amount `json:"tax_total"`
+	GrandTotal      money.Amount `json:"grand_total"`
+	LineItems       []LineItem   `json:"line_items"`
+	Taxes           []TaxLine    `json:"taxes"`
+	Days            []DayRate    `json:"days"`
+
+	// Converted is only filled on quotes requested with ?currency=
+	Converted *models.CurrencyConversion `json:"converted,omitempty"`
+}
+
+// Calculate prices a rental: each day at the car's weekday or weekend rent, adjusted by the
+// rate rule that applies that day, minus the membership discount percentage of the rent,
+// plus days × the driver's daily cost, plus the taxes on rent and driver cost
+func Calculate(input Input) Quote {
+	days := utils.RentalDays(input.StartRent, input.EndRent)
+
+	quote := Quote{
+		StartRent:  input.StartRent,
+		EndRent:    input.EndRent,
+		RentalDays: days,
+		LineItems:  []LineItem{},
+		Days:       make([]DayRate, 0, days),
+	}
+
+	// Consecutive days at the same rate share one rent line item
+	first := dateOf(input.StartRent)
+	for i := 0; i < days; i++ {
+		day := first.AddDate(0, 0, i)
+		dayRate := DayRate{
+			Date:      day.Format("2006-01-02"),
+			Weekend:   models.IsWeekendDay(day),
+			DailyRent: input.Car.RentForDay(day),
+		}
+		if rule := applicableRule(input.RateRules, input.Car.No, day); rule != nil {
+			dayRate.DailyRent = ruleRate(rule, dayRate.DailyRent)
+			dayRate.RateRuleID = &rule.No
+			dayRate.RateRule = rule.Name
+		}
+		quote.Days = append(quote.Days, dayRate)
+		quote.TotalCost += dayRate.DailyRent
+
+		item := rentLineItem(input.Car, dayRate)
+		last := len(quote.LineItems) - 1
+		if last >= 0 && quote.LineItems[last].Description == item.Description && quote.LineItems[last].UnitPrice == item.UnitPrice {
+			quote.LineItems[last].Quantity++
+			quote.LineItems[last].Amount += item.Amount
+			continue
+		}
+		quote.LineItems = append(quote.LineItems, item)
+	}
+
+	// Membership and voucher discounts apply to the rent only
+	membership := input.Membership
+	if membership != nil {
+		quote.DiscountPercent = membership.Discount
+		quote.Discount = quote.TotalCost.Percent(membership.Discount)
+	}
+
+	if input.Voucher != nil {
+		quote.VoucherCode = input.Voucher.Code
+		quote.VoucherDiscount = voucherDiscount(input.Voucher, quote.TotalCost, quote.Discount)
+
+		// A voucher that does not stack replaces the membership discount only when it is worth more
+		if !input.Voucher.StackWithMembership && membership != nil {
+			if quote.VoucherDiscount > quote.Discount {
+				membership = nil
+				quote.DiscountPercent = 0
+				quote.Discount = 0
+			} else {
+				quote.VoucherDiscount = 0
+			}
+		}
+	}
+
+	if membership != nil {
+		quote.LineItems = append(quote.LineItems, LineItem{
+			Code:        LINE_ITEM_MEMBERSHIP_DISCOUNT,
+			Description: membership.MembershipName + " membership discount",
+			Quantity:    1,
+			UnitPrice:   -quote.Discount,
+			Amount:      -quote.Discount,
+		})
+	}
+
+	if quote.VoucherDiscount > 0 {
+		quote.LineItems = append(quote.LineItems, LineItem{
+			Code:        LINE_ITEM_VOUCHER,
+			Description: "Voucher " + input.Voucher.Code,
+			Quantity:    1,
+			UnitPrice:   -quote.VoucherDiscount,
+			Amount:      -quote.VoucherDiscount,
+		})
+	}
+
+	if input.Driver != nil {
+		quote.TotalDriverCost = input.Driver.DailyCost.Times(days)
+		quote.LineItems = append(quote.LineItems, LineItem{
+			Code:        LINE_ITEM_DRIVER,
+			Description: "Driver " + input.Driver.Name,
+			Quantity:    days,
+			UnitPrice:   input.Driver.DailyCost,
+			Amount:      quote.TotalDriverCost,
+		})
+	}
+
+	rentalTaxes := CalculateTaxes(input.TaxRates, TAX_BASE_RENTAL, quote.TotalCost, quote.Discount+quote.VoucherDiscount)
+	driverTaxes := CalculateTaxes(input.TaxRates, TAX_BASE_DRIVER, quote.TotalDriverCost, 0)
+	quote.Taxes = append(rentalTaxes, driverTaxes...)
+	quote.RentalTax = TaxTotal(rentalTaxes)
+	quote.DriverTax = TaxTotal(driverTaxes)
+	quote.TaxTotal = quote.RentalTax + quote.DriverTax
+	for _, line := range quote.Taxes {
+		quote.LineItems = append(quote.LineItems, taxLineItem(line))
+	}
+
+	quote.GrandTotal = quote.TotalCost - quote.Discount - quote.VoucherDiscount + quote.TotalDriverCost + quote.TaxTotal
+	return quote
+}
+
+// Apply copies the priced amounts onto a booking
+func (q Quote) Apply(booking *models.Booking) {
+	booking.TotalCost = q.TotalCost
+	booking.Discount = q.Discount
+	booking.TotalDriverCost = q.TotalDriverCost
+	booking.VoucherDiscount = q.VoucherDiscount
+	booking.RentalTax = q.RentalTax
+	booking.DriverTax = q.DriverTax
+}
+
+// Convert shows the quote's totals in the currency of an exchange rate
+func (q *Quote) Convert(rate *models.ExchangeRate) {
+	q.Converted = rate.Convert(map[string]money.Amount{
+		"total_cost":        q.TotalCost,
+		"discount":          q.Discount,
+		"voucher_discount":  q.VoucherDiscount,
+		"total_driver_cost": q.TotalDriverCost,
+		"tax_total":         q.TaxTotal,
+		"grand_total":       q.GrandTotal,
+	})
+}
+
+// voucherDiscount is what a voucher takes off the rent. A stacking voucher is worked out
+// on the rent left after the membership discount; a fixed amount never exceeds that rent.
+func voucherDiscount(voucher *models.Voucher, rent, membershipDiscount money.Amount) money.Amount {
+	base := rent
+	if voucher.StackWithMembership {
+		base -= membershipDiscount
+	}
+
+	if voucher.DiscountType == models.VOUCHER_TYPE_PERCENT {
+		return base.Percent(voucher.Percent)
+	}
+	return money.Min(voucher.Amount, base)
+}
+
+// LoadRateRules returns the rules that may apply to a car between start and end. Pass a
+// carID of 0 to load the rules of every car.
+func LoadRateRules(db *gorm.DB, carID int, start, end time.Time) ([]models.RateRule, error) {
+	query := db.Where("start_date <= ? AND end_date >= ?", dateOf(end), dateOf(start))
+	if carID != 0 {
+		query = query.Where("car_id IS NULL OR car_id = ?", carID)
+	}
+
+	var rules []models.RateRule
+	err := query.Find(&rules).Error
+	return rules, err
+}
+
+// applicableRule picks the rule for a car on a day: highest priority first, then a
+// car-specific rule over a general one, then the most recently created
+func applicableRule(rules []models.RateRule, carID int, day time.Time) *models.RateRule {
+	var best *models.RateRule
+	for i := range rules {
+		rule := &rules[i]
+		if rule.CarID != nil && *rule.CarID != carID {
+			continue
+		}
+		if day.Before(dateOf(rule.StartDate)) || day.After(dateOf(rule.EndDate)) {
+			continue
+		}
+		if best == nil || outranks(rule, best) {
+			best = rule
+		}
+	}
+	return best
+}
+
+func outranks(rule, other *models.RateRule) bool {
+	if rule.Priority != other.Priority {
+		return rule.Priority > other.Priority
+	}
+	if (rule.CarID != nil) != (other.CarID != nil) {
+		return rule.CarID != nil
+	}
+	return rule.No > other.No
+}
+
+// ruleRate is the daily rent under a rule: the fixed rate if set, otherwise the day's rent scaled
+func ruleRate(rule *models.RateRule, dailyRent money.Amount) money.Amount {
+	if rule.FixedRate != nil {
+		return *rule.FixedRate
+	}
+	if rule.Multiplier != nil {
+		return dailyRent.Scale(*rule.Multiplier)
+	}
+	return dailyRent
+}
+
+func rentLineItem(car models.Car, dayRate DayRate) LineItem {
+	description := car.Name + " rental"
+	if car.WeekendRent != nil && dayRate.Weekend {
+		description = car.Name + " weekend rental"
+	}
+	if dayRate.RateRuleID != nil {
+		description = fmt.Sprintf("%s rental (%s)", car.Name, dayRate.RateRule)
+	}
+	return LineItem{
+		Code:        LINE_ITEM_RENT,
+		Description: description,
+		Quantity:    1,
+		UnitPrice:   dayRate.DailyRent,
+		Amount:      dayRate.DailyRent,
+	}
+}
+
+// dateOf drops the time of day, keeping the calendar date as written
+func dateOf(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,161 @@
+package pricing
+
+import (
+	"car-rental/pkg/models"
+	"car-rental/pkg/money"
+	"testing"
+	"time"
+)
+
+func TestCalculate(t *testing.T) {
+	weekendRent := money.Rupiah(150000)
+	car := models.Car{No: 1, Name: "Avanza", DailyRent: money.Rupiah(100000), WeekendRent: &weekendRent}
+	otherCar := 2
+	double := money.RateFromFloat(2)
+	fixedRate := money.Rupiah(10000)
+
+	// Monday to Friday: four weekdays and a Friday at the weekend rent
+	start := time.Date(2030, 3, 4, 10, 0, 0, 0, time.UTC)
+	end := time.Date(2030, 3, 8, 10, 0, 0, 0, time.UTC)
+
+	membership := &models.Membership{MembershipName: "Gold", Discount: money.RateOf(10)}
+	driver := &models.Driver{Name: "Budi", DailyCost: money.Rupiah(50000)}
+	vat := models.TaxRate{No: 1, Name: "PPN", Rate: money.RateOf(11), AppliesToRental: true, AppliesToDriver: true}
+
+	tests := []struct {
+		name  string
+		input Input
+		want  Quote
+	}{
+		{
+			name:  "weekday and weekend rent",
+			input: Input{Car: car},
+			want:  Quote{TotalCost: money.Rupiah(550000), GrandTotal: money.Rupiah(550000)},
+		},
+		{
+			name:  "membership discount",
+			input: Input{Car: car, Membership: membership},
+			want:  Quote{TotalCost: money.Rupiah(550000), Discount: money.Rupiah(55000), GrandTotal: money.Rupiah(495000)},
+		},
+		{
+			name:  "driver cost per day",
+			input: Input{Car: car, Driver: driver},
+			want:  Quote{TotalCost: money.Rupiah(550000), TotalDriverCost: money.Rupiah(250000), GrandTotal: money.Rupiah(800000)},
+		},
+		{
+			name: "rate rule on one day",
+			input: Input{Car: car, RateRules: []models.RateRule{
+				{No: 1, Name: "Holiday", StartDate: start.AddDate(0, 0, 2), EndDate: start.AddDate(0, 0, 2), Multiplier: &double},
+			}},
+			want: Quote{TotalCost: money.Rupiah(650000), GrandTotal: money.Rupiah(650000)},
+		},
+		{
+			name: "rate rule of another car",
+			input: Input{Car: car, RateRules: []models.RateRule{
+				{No: 1, Name: "Promo", StartDate: start, EndDate: end, CarID: &otherCar, FixedRate: &fixedRate},
+			}},
+			want: Quote{TotalCost: money.Rupiah(550000), GrandTotal: money.Rupiah(550000)},
+		},
+		{
+			name: "percent voucher worth more than the membership replaces it",
+			input: Input{Car: car, Membership: membership, Voucher: &models.Voucher{
+				Code: "SAVE20", DiscountType: models.VOUCHER_TYPE_PERCENT, Percent: money.RateOf(20),
+			}},
+			want: Quote{TotalCost: money.Rupiah(550000), VoucherDiscount: money.Rupiah(110000), GrandTotal: money.Rupiah(440000)},
+		},
+		{
+			name: "fixed voucher worth less than the membership is dropped",
+			input: Input{Car: car, Membership: membership, Voucher: &models.Voucher{
+				Code: "SAVE50K", DiscountType: models.VOUCHER_TYPE_FIXED, Amount: money.Rupiah(50000),
+			}},
+			want: Quote{TotalCost: money.Rupiah(550000), Discount: money.Rupiah(55000), GrandTotal: money.Rupiah(495000)},
+		},
+		{
+			name: "stacking voucher applies to the rent after the membership discount",
+			input: Input{Car: car, Membership: membership, Voucher: &models.Voucher{
+				Code: "STACK10", DiscountType: models.VOUCHER_TYPE_PERCENT, Percent: money.RateOf(10), StackWithMembership: true,
+			}},
+			want: Quote{TotalCost: money.Rupiah(550000), Discount: money.Rupiah(55000), VoucherDiscount: money.Rupiah(49500), GrandTotal: money.Rupiah(445500)},
+		},
+		{
+			name: "fixed voucher never exceeds the rent",
+			input: Input{Car: car, Voucher: &models.Voucher{
+				Code: "FREE", DiscountType: models.VOUCHER_TYPE_FIXED, Amount: money.Rupiah(1000000),
+			}},
+			want: Quote{TotalCost: money.Rupiah(550000), VoucherDiscount: money.Rupiah(550000), GrandTotal: 0},
+		},
+		{
+			name:  "tax after discount on rent and driver",
+			input: Input{Car: car, Membership: membership, Driver: driver, TaxRates: []models.TaxRate{vat}},
+			want: Quote{
+				TotalCost: money.Rupiah(550000), Discount: money.Rupiah(55000), TotalDriverCost: money.Rupiah(250000),
+				RentalTax: money.Rupiah(54450), DriverTax: money.Rupiah(27500), TaxTotal: money.Rupiah(81950), GrandTotal: money.Rupiah(826950),
+			},
+		},
+		{
+			name: "tax before discount",
+			input: Input{Car: car, Membership: membership, TaxRates: []models.TaxRate{
+				{No: 1, Name: "PPN", Rate: money.RateOf(11), AppliesToRental: true, AppliedBeforeDiscount: true},
+			}},
+			want: Quote{
+				TotalCost: money.Rupiah(550000), Discount: money.Rupiah(55000),
+				RentalTax: money.Rupiah(60500), TaxTotal: money.Rupiah(60500), GrandTotal: money.Rupiah(555500),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt.input.StartRent, tt.input.EndRent = start, end
+		got := Calculate(tt.input)
+
+		if got.RentalDays != 5 || len(got.Days) != 5 {
+			t.Errorf("%s: %d rental days with %d day rates, want 5", tt.name, got.RentalDays, len(got.Days))
+		}
+		if got.TotalCost != tt.want.TotalCost || got.Discount != tt.want.Discount || got.VoucherDiscount != tt.want.VoucherDiscount ||
+			got.TotalDriverCost != tt.want.TotalDriverCost || got.RentalTax != tt.want.RentalTax || got.DriverTax != tt.want.DriverTax ||
+			got.TaxTotal != tt.want.TaxTotal || got.GrandTotal != tt.want.GrandTotal {
+			t.Errorf("%s: got rent %s, discount %s, voucher %s, driver %s, rental tax %s, driver tax %s, tax %s, total %s; "+
+				"want rent %s, discount %s, voucher %s, driver %s, rental tax %s, driver tax %s, tax %s, total %s",
+				tt.name, got.TotalCost, got.Discount, got.VoucherDiscount, got.TotalDriverCost, got.RentalTax, got.DriverTax, got.TaxTotal, got.GrandTotal,
+				tt.want.TotalCost, tt.want.Discount, tt.want.VoucherDiscount, tt.want.TotalDriverCost, tt.want.RentalTax, tt.want.DriverTax, tt.want.TaxTotal, tt.want.GrandTotal)
+		}
+
+		// The line items always add up to the grand total
+		var sum money.Amount
+		for _, item := range got.LineItems {
+			sum += item.Amount
+		}
+		if sum != got.GrandTotal {
+			t.Errorf("%s: line items add up to %s, want the grand total %s", tt.name, sum, got.GrandTotal)
+		}
+	}
+}
+
+func TestCalculateGroupsConsecutiveDaysAtTheSameRate(t *testing.T) {
+	weekendRent := money.Rupiah(150000)
+	car := models.Car{No: 1, Name: "Avanza", DailyRent: money.Rupiah(100000), WeekendRent: &weekendRent}
+
+	// Monday to the next Monday: four weekdays, three weekend days, one weekday
+	quote := Calculate(Input{
+		Car:       car,
+		StartRent: time.Date(2030, 3, 4, 10, 0, 0, 0, time.UTC),
+		EndRent:   time.Date(2030, 3, 11, 10, 0, 0, 0, time.UTC),
+	})
+
+	want := []struct {
+		description string
+		quantity    int
+	}{
+		{"Avanza rental", 4},
+		{"Avanza weekend rental", 3},
+		{"Avanza rental", 1},
+	}
+	if len(quote.LineItems) != len(want) {
+		t.Fatalf("%d line items, want %d: %+v", len(quote.LineItems), len(want), quote.LineItems)
+	}
+	for i, w := range want {
+		if item := quote.LineItems[i]; item.Description != w.description || item.Quantity != w.quantity {
+			t.Errorf("line item %d = %q × %d, want %q × %d", i, item.Description, item.Quantity, w.description, w.quantity)
+		}
+	}
+}
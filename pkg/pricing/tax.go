@@ -0,0 +1,93 @@
+package pricing
+
+import (
+	"car-rental/pkg/models"
+	"car-rental/pkg/money"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// Parts of the price a tax is charged on
+const (
+	TAX_BASE_RENTAL = "rental"
+	TAX_BASE_DRIVER = "driver"
+	TAX_BASE_FEES   = "fees"
+)
+
+// TaxLine is the tax one rate adds to one part of the price
+type TaxLine struct {
+	TaxRateID int          `json:"tax_rate_id"`
+	Name      string       `json:"name"`
+	Rate      money.Rate   `json:"rate"`
+	AppliesTo string       `json:"applies_to"`
+	Base      money.Amount `json:"base"`
+	Amount    money.Amount `json:"amount"`
+}
+
+// LoadTaxRates returns every configured tax rate
+func LoadTaxRates(db *gorm.DB) ([]models.TaxRate, error) {
+	var rates []models.TaxRate
+	err := db.Order("no").Find(&rates).Error
+	return rates, err
+}
+
+// CalculateTaxes charges every rate that applies to one part of the price. Discount is what
+// the customer was let off that part; rates applied after discount are charged on the rest.
+func CalculateTaxes(rates []models.TaxRate, appliesTo string, amount, discount money.Amount) []TaxLine {
+	lines := []TaxLine{}
+	for _, rate := range rates {
+		if !taxApplies(rate, appliesTo) {
+			continue
+		}
+
+		base := amount
+		if !rate.AppliedBeforeDiscount {
+			base -= discount
+		}
+		if base <= 0 {
+			continue
+		}
+
+		lines = append(lines, TaxLine{
+			TaxRateID: rate.No,
+			Name:      rate.Name,
+			Rate:      rate.Rate,
+			AppliesTo: appliesTo,
+			Base:      base,
+			Amount:    base.Percent(rate.Rate),
+		})
+	}
+	return lines
+}
+
+// TaxTotal adds up tax lines
+func TaxTotal(lines []TaxLine) money.Amount {
+	var total money.Amount
+	for _, line := range lines {
+		total += line.Amount
+	}
+	return total
+}
+
+func taxApplies(rate models.TaxRate, appliesTo string) bool {
+	switch appliesTo {
+	case TAX_BASE_RENTAL:
+		return rate.AppliesToRental
+	case TAX_BASE_DRIVER:
+		return rate.AppliesToDriver
+	case TAX_BASE_FEES:
+		return rate.AppliesToFees
+	}
+	return false
+}
+
+func taxLineItem(line TaxLine) LineItem {
+	return LineItem{
+		Code:        LINE_ITEM_TAX,
+		Description: fmt.Sprintf("%s %s%% on %s", line.Name, line.Rate, line.AppliesTo),
+		Quantity:    1,
+		UnitPrice:   line.Amount,
+		Amount:      line.Amount,
+	}
+}
@@ -0,0 +1,86 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// How often idle buckets are dropped from a MemoryStore
+const SWEEP_INTERVAL = time.Minute
+
+// Result is the outcome of taking a token
+type Result struct {
+	Allowed    bool
+	Remaining  int           // Whole tokens left after this request
+	RetryAfter time.Duration // Wait until the next token when refused
+}
+
+// Store keeps the token buckets. Take must be safe for concurrent use.
+type Store interface {
+	Take(key string, limit Limit, now time.Time) Result
+}
+
+// MemoryStore keeps buckets in process memory. Each node counts on its own, so it only
+// enforces the quota exactly for single-node deployments.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	fullAt  time.Time // When the bucket is full again if left alone
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: map[string]*bucket{}}
+}
+
+// Take refills the key's bucket for the time passed and takes one token if there is one
+func (s *MemoryStore) Take(key string, limit Limit, now time.Time) Result {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweep(now)
+
+	capacity := float64(limit.Requests)
+	perToken := limit.Per / time.Duration(limit.Requests)
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: capacity, updated: now}
+		s.buckets[key] = b
+	} else if elapsed := now.Sub(b.updated); elapsed > 0 {
+		b.tokens = math.Min(capacity, b.tokens+float64(elapsed)/float64(perToken))
+		b.updated = now
+	}
+
+	result := Result{}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = time.Duration((1 - b.tokens) * float64(perToken))
+	}
+	result.Remaining = int(b.tokens)
+	b.fullAt = now.Add(time.Duration((capacity - b.tokens) * float64(perToken)))
+
+	return result
+}
+
+// sweep drops buckets that have refilled completely; a new bucket starts full anyway
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < SWEEP_INTERVAL {
+		return
+	}
+	s.lastSweep = now
+
+	for key, b := range s.buckets {
+		if !now.Before(b.fullAt) {
+			delete(s.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreTake(t *testing.T) {
+	// Three requests per three seconds: a full bucket of three, then one token a second
+	limit := Limit{Requests: 3, Per: 3 * time.Second}
+	start := time.Date(2030, 3, 10, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		key            string
+		after          time.Duration
+		wantAllowed    bool
+		wantRemaining  int
+		wantRetryAfter time.Duration
+	}{
+		{"first request of a burst", "a", 0, true, 2, 0},
+		{"second request of a burst", "a", 0, true, 1, 0},
+		{"last token", "a", 0, true, 0, 0},
+		{"empty bucket", "a", 0, false, 0, time.Second},
+		{"other clients have their own bucket", "b", 0, true, 2, 0},
+		{"half a token refilled", "a", 500 * time.Millisecond, false, 0, 500 * time.Millisecond},
+		{"a whole token refilled", "a", time.Second, true, 0, 0},
+		{"the refill is capped at the limit", "a", time.Hour, true, 2, 0},
+		{"after a long pause", "a", time.Hour, true, 1, 0},
+		{"after a long pause, last token", "a", time.Hour, true, 0, 0},
+		{"after a long pause, empty", "a", time.Hour, false, 0, time.Second},
+	}
+
+	store := NewMemoryStore()
+	for _, tt := range tests {
+		got := store.Take(tt.key, limit, start.Add(tt.after))
+		if got.Allowed != tt.wantAllowed || got.Remaining != tt.wantRemaining || got.RetryAfter != tt.wantRetryAfter {
+			t.Errorf("%s: got allowed %v, %d remaining, retry after %s; want %v, %d, %s",
+				tt.name, got.Allowed, got.Remaining, got.RetryAfter, tt.wantAllowed, tt.wantRemaining, tt.wantRetryAfter)
+		}
+	}
+}
+
+func TestMemoryStoreSweepsFullBuckets(t *testing.T) {
+	limit := Limit{Requests: 2, Per: time.Minute}
+	start := time.Date(2030, 3, 10, 9, 0, 0, 0, time.UTC)
+
+	store := NewMemoryStore()
+	store.Take("idle", limit, start)
+	store.Take("busy", limit, start.Add(2*SWEEP_INTERVAL))
+	store.Take("busy", limit, start.Add(2*SWEEP_INTERVAL))
+
+	// The idle bucket has refilled and is dropped; the busy one is still draining
+	store.Take("other", limit, start.Add(2*SWEEP_INTERVAL+time.Second))
+	if _, ok := store.buckets["idle"]; ok {
+		t.Errorf("the full bucket was kept")
+	}
+	if _, ok := store.buckets["busy"]; !ok {
+		t.Errorf("the draining bucket was dropped")
+	}
+}
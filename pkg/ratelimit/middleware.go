@@ -0,0 +1,58 @@
+package ratelimit
+
+import (
+	"car-rental/pkg/auth"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Limiter applies the configured quotas to requests
+type Limiter struct {
+	store  Store
+	config Config
+}
+
+// Default is the limiter used by the routes, set up in main
+var Default *Limiter
+
+func New(store Store, config Config) *Limiter {
+	return &Limiter{store: store, config: config}
+}
+
+// Config returns the quotas in use
+func (l *Limiter) Config() Config {
+	return l.config
+}
+
+// Middleware limits the requests of each client to a group's quota. Requests over it get
+// 429 with Retry-After. Put it after the authentication middleware so clients are keyed
+// by API key or user rather than by IP.
+func (l *Limiter) Middleware(group string) gin.HandlerFunc {
+	limit := l.config.For(group)
+
+	return func(c *gin.Context) {
+		if limit.Disabled() {
+			c.Next()
+			return
+		}
+
+		result := l.store.Take(group+"|"+auth.ClientKey(c), limit, time.Now())
+		c.Header("X-RateLimit-Limit", strconv.Itoa(limit.Requests))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+
+		if !result.Allowed {
+			retryAfter := int(math.Ceil(result.RetryAfter.Seconds()))
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+				"error":       "Too many requests, try again later",
+				"retry_after": retryAfter,
+			})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,124 @@
+// Package ratelimit throttles clients with token buckets kept per route group and client.
+//
+// A bucket holds up to Limit.Requests tokens and refills evenly over Limit.Per, so a client
+// can burst up to the full quota and then continues at the average rate. Buckets live in a
+// Store; MemoryStore suits a single node, and a shared store can be plugged in for more.
+package ratelimit
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Route groups with their own quota
+const (
+	GROUP_DEFAULT  = "default"  // Every other API route
+	GROUP_AUTH     = "auth"     // Sign-up, login and token refresh, keyed by IP
+	GROUP_BOOKINGS = "bookings" // Booking routes, including the /me and partner ones
+	GROUP_PARTNER  = "partner"  // Partner routes, keyed by API key
+)
+
+// Default quotas, overridden by RATE_LIMIT, RATE_LIMIT_AUTH, RATE_LIMIT_BOOKINGS and
+// RATE_LIMIT_PARTNER
+var (
+	DEFAULT_LIMIT          = Limit{Requests: 300, Per: time.Minute}
+	DEFAULT_AUTH_LIMIT     = Limit{Requests: 10, Per: time.Minute}
+	DEFAULT_BOOKINGS_LIMIT = Limit{Requests: 60, Per: time.Minute}
+	DEFAULT_PARTNER_LIMIT  = Limit{Requests: 600, Per: time.Minute}
+)
+
+// Limit allows Requests requests per Per. A zero limit lets everything through.
+type Limit struct {
+	Requests int
+	Per      time.Duration
+}
+
+// Disabled reports whether the limit lets everything through
+func (l Limit) Disabled() bool {
+	return l.Requests <= 0 || l.Per <= 0
+}
+
+// String writes the limit the way ParseLimit reads it, e.g. "60/1m0s"
+func (l Limit) String() string {
+	if l.Disabled() {
+		return "off"
+	}
+	return fmt.Sprintf("%d/%s", l.Requests, l.Per)
+}
+
+// ParseLimit reads a limit such as "60/m", "10/s", "1000/h" or "100/30s". "off" disables
+// the limit.
+func ParseLimit(s string) (Limit, error) {
+	s = strings.TrimSpace(strings.ToLower(s))
+	if s == "off" {
+		return Limit{}, nil
+	}
+
+	count, period, found := strings.Cut(s, "/")
+	if !found {
+		return Limit{}, fmt.Errorf("%q is not requests/period", s)
+	}
+
+	requests, err := strconv.Atoi(strings.TrimSpace(count))
+	if err != nil || requests <= 0 {
+		return Limit{}, fmt.Errorf("%q does not start with a positive number of requests", s)
+	}
+
+	var per time.Duration
+	switch period = strings.TrimSpace(period); period {
+	case "s":
+		per = time.Second
+	case "m":
+		per = time.Minute
+	case "h":
+		per = time.Hour
+	default:
+		per, err = time.ParseDuration(period)
+		if err != nil || per <= 0 {
+			return Limit{}, fmt.Errorf("%q does not end with a period such as s, m, h or 30s", s)
+		}
+	}
+
+	return Limit{Requests: requests, Per: per}, nil
+}
+
+// Config holds the quota of each route group
+type Config struct {
+	Groups map[string]Limit
+}
+
+// For returns the quota of a group, or the default quota for groups without one
+func (c Config) For(group string) Limit {
+	if limit, ok := c.Groups[group]; ok {
+		return limit
+	}
+	return c.Groups[GROUP_DEFAULT]
+}
+
+// ConfigFromEnv reads RATE_LIMIT, RATE_LIMIT_AUTH, RATE_LIMIT_BOOKINGS and RATE_LIMIT_PARTNER
+func ConfigFromEnv() Config {
+	return Config{Groups: map[string]Limit{
+		GROUP_DEFAULT:  getEnvLimit("RATE_LIMIT", DEFAULT_LIMIT),
+		GROUP_AUTH:     getEnvLimit("RATE_LIMIT_AUTH", DEFAULT_AUTH_LIMIT),
+		GROUP_BOOKINGS: getEnvLimit("RATE_LIMIT_BOOKINGS", DEFAULT_BOOKINGS_LIMIT),
+		GROUP_PARTNER:  getEnvLimit("RATE_LIMIT_PARTNER", DEFAULT_PARTNER_LIMIT),
+	}}
+}
+
+func getEnvLimit(key string, fallback Limit) Limit {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	limit, err := ParseLimit(value)
+	if err != nil {
+		log.Printf("Invalid %s value %q, using default %v", key, value, fallback)
+		return fallback
+	}
+	return limit
+}
@@ -1,20 +1,101 @@
 package routes
 
 import (
+	"car-rental/pkg/auth"
+	"car-rental/pkg/database"
+	"car-rental/pkg/gateway"
 	"car-rental/pkg/handlers"
+	"car-rental/pkg/idempotency"
+	"car-rental/pkg/models"
+	"car-rental/pkg/ratelimit"
+	"car-rental/pkg/utils"
 
 	"github.com/gin-gonic/gin"
 )
 
 func SetupRoutes(r *gin.Engine) {
-	// Create API v1 group
-	v1 := r.Group("/api/v1")
+	// Request quotas per route group; each route counts against exactly one group, after
+	// authentication so signed-in clients are counted per user rather than per IP
+	limiter := ratelimit.Default
+	defaultLimit := limiter.Middleware(ratelimit.GROUP_DEFAULT)
+	bookingsLimit := limiter.Middleware(ratelimit.GROUP_BOOKINGS)
 
-	// Create API v2 group
-	v2 := r.Group("/api/v2")
+	// Routes whose retries with the same Idempotency-Key replay the first response
+	idempotent := idempotency.Default.Middleware()
+
+	// Create API v1 group; only the car catalogue is public, everything else needs an access
+	// token and the same role as its v2 route. Routes on v1Default and v2Default count
+	// against the default quota; booking routes count against the bookings quota instead.
+	v1Public := r.Group("/api/v1", defaultLimit)
+	v1 := r.Group("/api/v1", auth.RequireToken(auth.Tokens))
+	v1Default := v1.Group("", defaultLimit)
+
+	// Create API v2 group; every v2 route needs an access token unless it is registered on v2Public
+	v2Public := r.Group("/api/v2")
+	v2 := r.Group("/api/v2", auth.RequireToken(auth.Tokens))
+	v2Default := v2.Group("", defaultLimit)
+
+	// Access policies of the v2 routes. Roles under OwnRecords only see the records linked
+	// to their account; any other role gets 403.
+	adminOnly := auth.Authorize(auth.Policy{Roles: []string{models.ROLE_ADMIN}})
+	staffOnly := auth.Authorize(auth.Policy{Roles: []string{models.ROLE_ADMIN, models.ROLE_STAFF}})
+	anyRole := auth.Authorize(auth.Policy{Roles: models.UserRoles})
+	staffOrOwnBookings := auth.Authorize(auth.Policy{
+		Roles:      []string{models.ROLE_ADMIN, models.ROLE_STAFF},
+		OwnRecords: []string{models.ROLE_DRIVER, models.ROLE_CUSTOMER},
+	})
+	staffOrOwnInvoice := auth.Authorize(auth.Policy{
+		Roles:      []string{models.ROLE_ADMIN, models.ROLE_STAFF},
+		OwnRecords: []string{models.ROLE_CUSTOMER},
+	})
+	staffOrOwnDriver := auth.Authorize(auth.Policy{
+		Roles:      []string{models.ROLE_ADMIN, models.ROLE_STAFF},
+		OwnRecords: []string{models.ROLE_DRIVER},
+	})
+	customerOnly := auth.Authorize(auth.Policy{OwnRecords: []string{models.ROLE_CUSTOMER}})
+
+	// v2 updates must name the version they were made against; v1 clients may leave it out
+	ifMatch := utils.RequireIfMatch()
+
+	// Sign-in routes (v2 only, public)
+	authV2 := v2Public.Group("/auth", limiter.Middleware(ratelimit.GROUP_AUTH))
+	{
+		authV2.POST("/signup", handlers.Signup)
+		authV2.POST("/login", handlers.Login)
+		authV2.POST("/refresh", handlers.RefreshTokens)
+		authV2.POST("/logout", handlers.Logout)
+	}
+
+	// Customer self-service routes (v2 only), acting for the customer of the signed-in user
+	meV2 := v2.Group("/me", customerOnly)
+	{
+		meDefaultV2 := meV2.Group("", defaultLimit)
+		meDefaultV2.GET("", handlers.GetMe)
+		meDefaultV2.GET("/cars/available", handlers.GetAvailableCars)
+		meDefaultV2.PUT("/membership/:membership_id", handlers.SubscribeMeToMembership)
+		meDefaultV2.DELETE("/membership", handlers.UnsubscribeMeFromMembership)
+
+		myBookingsV2 := meV2.Group("/bookings", bookingsLimit)
+		myBookingsV2.GET("", handlers.GetBookings)
+		myBookingsV2.GET("/:id", handlers.GetBooking)
+		myBookingsV2.POST("/quote", handlers.QuoteMyBooking)
+		myBookingsV2.POST("", idempotent, handlers.CreateMyBooking)
+		myBookingsV2.PUT("/:id", ifMatch, handlers.UpdateMyBooking)
+		myBookingsV2.POST("/:id/cancel", handlers.CancelMyBooking)
+		myBookingsV2.GET("/:id/invoice", handlers.GetBookingInvoice)
+	}
+
+	// Staff user routes (v2 only)
+	usersV2 := v2Default.Group("/users", adminOnly)
+	{
+		usersV2.GET("", handlers.GetUsers)
+		usersV2.GET("/:id", handlers.GetUser)
+		usersV2.POST("", handlers.CreateUser)
+		usersV2.PUT("/:id", handlers.UpdateUser)
+	}
 
 	// Customer routes for v1 - Basic CRUD only
-	customersV1 := v1.Group("/customers")
+	customersV1 := v1Default.Group("/customers", staffOnly)
 	{
 		customersV1.GET("", handlers.GetCustomers)
 		customersV1.GET("/:id", handlers.GetCustomer)
@@ -24,12 +105,12 @@ func SetupRoutes(r *gin.Engine) {
 	}
 
 	// Customer routes for v2 - Full functionality
-	customersV2 := v2.Group("/customers")
+	customersV2 := v2Default.Group("/customers", staffOnly)
 	{
 		customersV2.GET("", handlers.GetCustomers)
 		customersV2.GET("/:id", handlers.GetCustomer)
 		customersV2.POST("", handlers.CreateCustomer)
-		customersV2.PUT("/:id", handlers.UpdateCustomer)
+		customersV2.PUT("/:id", ifMatch, handlers.UpdateCustomer)
 		customersV2.DELETE("/:id", handlers.DeleteCustomer)
 
 		// Membership subscription endpoints
@@ -37,69 +118,184 @@ func SetupRoutes(r *gin.Engine) {
 		customersV2.DELETE("/:id/unsubscribe", handlers.UnsubscribeFromMembership)
 	}
 
-	// Car routes for v1 - Basic CRUD only
-	carsV1 := v1.Group("/cars")
+	// Car routes for v1 - Basic CRUD only; the catalogue can be read without signing in
+	carsV1Public := v1Public.Group("/cars")
+	{
+		carsV1Public.GET("", handlers.GetCars)
+		carsV1Public.GET("/:id", handlers.GetCar)
+	}
+	carsV1 := v1Default.Group("/cars", adminOnly)
 	{
-		carsV1.GET("", handlers.GetCars)
-		carsV1.GET("/:id", handlers.GetCar)
 		carsV1.POST("", handlers.CreateCar)
 		carsV1.PUT("/:id", handlers.UpdateCar)
 		carsV1.DELETE("/:id", handlers.DeleteCar)
 	}
 
 	// Car routes for v2 - Full functionality
-	carsV2 := v2.Group("/cars")
+	carsV2 := v2Default.Group("/cars")
 	{
-		carsV2.GET("", handlers.GetCars)
-		carsV2.GET("/:id", handlers.GetCar)
-		carsV2.POST("", handlers.CreateCar)
-		carsV2.PUT("/:id", handlers.UpdateCar)
-		carsV2.DELETE("/:id", handlers.DeleteCar)
+		carsV2.GET("", anyRole, handlers.GetCars)
+		carsV2.GET("/available", anyRole, handlers.GetAvailableCars)
+		carsV2.GET("/:id", anyRole, handlers.GetCar)
+		carsV2.POST("", adminOnly, handlers.CreateCar)
+		carsV2.PUT("/:id", adminOnly, ifMatch, handlers.UpdateCar)
+		carsV2.DELETE("/:id", adminOnly, handlers.DeleteCar)
+
+		// Physical vehicle units of a car model
+		carsV2.GET("/:id/units", staffOnly, handlers.GetCarUnits)
+		carsV2.GET("/:id/units/:unit_id", staffOnly, handlers.GetCarUnit)
+		carsV2.POST("/:id/units", adminOnly, handlers.CreateCarUnit)
+		carsV2.PUT("/:id/units/:unit_id", adminOnly, handlers.UpdateCarUnit)
+		carsV2.DELETE("/:id/units/:unit_id", adminOnly, handlers.DeleteCarUnit)
 	}
 
 	// Booking routes for v1 - Basic CRUD only
-	bookingsV1 := v1.Group("/bookings")
+	bookingsV1 := v1.Group("/bookings", bookingsLimit)
 	{
-		bookingsV1.GET("", handlers.GetBookings)
-		bookingsV1.GET("/:id", handlers.GetBooking)
-		bookingsV1.POST("", handlers.CreateBooking)
-		bookingsV1.PUT("/:id", handlers.UpdateBooking)
-		bookingsV1.PUT("/:id/finish", handlers.FinishBooking)
+		bookingsV1.GET("", staffOrOwnBookings, handlers.GetBookings)
+		bookingsV1.GET("/:id", staffOrOwnBookings, handlers.GetBooking)
+		bookingsV1.POST("", staffOnly, idempotent, handlers.CreateBooking)
+		bookingsV1.PUT("/:id", staffOnly, handlers.UpdateBooking)
+		bookingsV1.PUT("/:id/finish", staffOnly, idempotent, handlers.FinishBooking)
 
-		bookingsV1.DELETE("/:id", handlers.DeleteBooking)
+		bookingsV1.DELETE("/:id", staffOnly, handlers.DeleteBooking)
 	}
 
 	// Booking routes for v2 - Full functionality
-	bookingsV2 := v2.Group("/bookings")
+	bookingsV2 := v2.Group("/bookings", bookingsLimit)
 	{
-		bookingsV2.GET("", handlers.GetBookings)
-		bookingsV2.GET("/:id", handlers.GetBooking)
-		bookingsV2.POST("", handlers.CreateBooking)
-		bookingsV2.PUT("/:id", handlers.UpdateBooking)
-		bookingsV2.DELETE("/:id", handlers.DeleteBooking)
-		bookingsV2.PUT("/:id/finish", handlers.FinishBooking)
+		bookingsV2.GET("", staffOrOwnBookings, handlers.GetBookings)
+		bookingsV2.GET("/:id", staffOrOwnBookings, handlers.GetBooking)
+		bookingsV2.POST("", staffOnly, idempotent, handlers.CreateBooking)
+		bookingsV2.POST("/quote", staffOnly, handlers.QuoteBooking)
+		bookingsV2.PUT("/:id", staffOnly, ifMatch, handlers.UpdateBooking)
+		bookingsV2.DELETE("/:id", staffOnly, handlers.DeleteBooking)
+		bookingsV2.PUT("/:id/finish", staffOnly, idempotent, handlers.FinishBooking)
+		bookingsV2.PUT("/:id/unit", staffOnly, handlers.AssignCarUnit)
+
+		// Booking lifecycle transitions
+		bookingsV2.POST("/:id/confirm", staffOnly, handlers.ConfirmBooking)
+		bookingsV2.POST("/:id/pickup", staffOnly, handlers.PickUpBooking)
+		bookingsV2.POST("/:id/return", staffOnly, idempotent, handlers.ReturnBooking)
+		bookingsV2.POST("/:id/close", staffOnly, handlers.CloseBooking)
+		bookingsV2.POST("/:id/cancel", staffOnly, handlers.CancelBooking)
+
+		// Booking payment ledger
+		bookingsV2.GET("/:id/payments", staffOnly, handlers.GetBookingPayments)
+		bookingsV2.POST("/:id/payments", staffOnly, idempotent, handlers.CreateBookingPayment)
+		bookingsV2.POST("/:id/refunds", staffOnly, idempotent, handlers.CreateBookingRefund)
+
+		// Online payments through a payment gateway
+		bookingsV2.GET("/:id/charges", staffOnly, handlers.GetBookingCharges)
+		bookingsV2.POST("/:id/charges", staffOnly, idempotent, handlers.CreateBookingCharge)
+		bookingsV2.POST("/:id/charges/:charge_id/refund", staffOnly, idempotent, handlers.RefundBookingCharge)
+
+		// Invoice of a returned booking
+		bookingsV2.GET("/:id/invoice", staffOrOwnInvoice, handlers.GetBookingInvoice)
 
 		// Booking Type sub-routes (read-only)
-		bookingsV2.GET("/types", handlers.GetBookingTypes)
-		bookingsV2.GET("/types/:id", handlers.GetBookingType)
+		bookingsV2.GET("/types", anyRole, handlers.GetBookingTypes)
+		bookingsV2.GET("/types/:id", anyRole, handlers.GetBookingType)
 	}
 
 	// Membership routes (v2 only)
-	membershipsV2 := v2.Group("/memberships")
+	membershipsV2 := v2Default.Group("/memberships", anyRole)
 	{
 		membershipsV2.GET("", handlers.GetMemberships)
 		membershipsV2.GET("/:id", handlers.GetMembership)
 	}
 
+	// Cancellation policy routes (v2 only)
+	cancellationPoliciesV2 := v2Default.Group("/cancellation-policies")
+	{
+		cancellationPoliciesV2.GET("", anyRole, handlers.GetCancellationPolicyTiers)
+		cancellationPoliciesV2.GET("/:id", anyRole, handlers.GetCancellationPolicyTier)
+		cancellationPoliciesV2.POST("", adminOnly, handlers.CreateCancellationPolicyTier)
+		cancellationPoliciesV2.PUT("/:id", adminOnly, handlers.UpdateCancellationPolicyTier)
+		cancellationPoliciesV2.DELETE("/:id", adminOnly, handlers.DeleteCancellationPolicyTier)
+	}
+
+	// Pricing routes (v2 only)
+	pricingV2 := v2Default.Group("/pricing")
+	{
+		pricingV2.GET("/rules", staffOnly, handlers.GetRateRules)
+		pricingV2.GET("/rules/:id", staffOnly, handlers.GetRateRule)
+		pricingV2.POST("/rules", adminOnly, handlers.CreateRateRule)
+		pricingV2.PUT("/rules/:id", adminOnly, handlers.UpdateRateRule)
+		pricingV2.DELETE("/rules/:id", adminOnly, handlers.DeleteRateRule)
+
+		// Tax rates charged on rent, driver cost and late fees
+		pricingV2.GET("/taxes", staffOnly, handlers.GetTaxRates)
+		pricingV2.GET("/taxes/:id", staffOnly, handlers.GetTaxRate)
+		pricingV2.POST("/taxes", adminOnly, handlers.CreateTaxRate)
+		pricingV2.PUT("/taxes/:id", adminOnly, handlers.UpdateTaxRate)
+		pricingV2.DELETE("/taxes/:id", adminOnly, handlers.DeleteTaxRate)
+	}
+
+	// Payment gateway routes (v2 only, public: webhooks are checked by their signature)
+	paymentsV2 := v2Public.Group("/payments")
+	{
+		paymentsV2.POST("/webhooks/:provider", handlers.HandlePaymentWebhook)
+	}
+
+	// Settling fake charges is staff-only and only exists while the fake provider is enabled
+	if _, ok := gateway.Get(gateway.FAKE_PROVIDER_NAME); ok {
+		v2Default.POST("/payments/fake/charges/:charge_id/complete", staffOnly, handlers.CompleteFakeCharge)
+	}
+
+	// Voucher routes (v2 only)
+	vouchersV2 := v2Default.Group("/vouchers")
+	{
+		vouchersV2.GET("", staffOnly, handlers.GetVouchers)
+		vouchersV2.GET("/:id", staffOnly, handlers.GetVoucher)
+		vouchersV2.POST("", adminOnly, handlers.CreateVoucher)
+		vouchersV2.PUT("/:id", adminOnly, handlers.UpdateVoucher)
+		vouchersV2.DELETE("/:id", adminOnly, handlers.DeleteVoucher)
+		vouchersV2.GET("/:id/redemptions", staffOnly, handlers.GetVoucherRedemptions)
+	}
+
 	// Driver routes (v2 only)
-	driversV2 := v2.Group("/drivers")
-	{
-		driversV2.GET("", handlers.GetDrivers)
-		driversV2.GET("/:id", handlers.GetDriver)
-		driversV2.POST("", handlers.CreateDriver)
-		driversV2.PUT("/:id", handlers.UpdateDriver)
-		driversV2.DELETE("/:id", handlers.DeleteDriver)
-		driversV2.GET("/:id/incentives", handlers.GetDriverIncentives)
+	driversV2 := v2Default.Group("/drivers")
+	{
+		driversV2.GET("", staffOnly, handlers.GetDrivers)
+		driversV2.GET("/:id", staffOrOwnDriver, handlers.GetDriver)
+		driversV2.POST("", adminOnly, handlers.CreateDriver)
+		driversV2.PUT("/:id", adminOnly, ifMatch, handlers.UpdateDriver)
+		driversV2.DELETE("/:id", adminOnly, handlers.DeleteDriver)
+		driversV2.GET("/:id/incentives", staffOrOwnDriver, handlers.GetDriverIncentives)
+	}
+
+	// Admin routes (v2 only)
+	adminV2 := v2Default.Group("/admin", adminOnly)
+	{
+		adminV2.GET("/jobs/runs", handlers.GetSchedulerRuns)
+		adminV2.POST("/jobs/run", handlers.RunSchedulerNow)
+
+		// Exchange rates for showing prices in other currencies
+		adminV2.GET("/exchange-rates", handlers.GetExchangeRates)
+		adminV2.GET("/exchange-rates/:id", handlers.GetExchangeRate)
+		adminV2.POST("/exchange-rates", handlers.CreateExchangeRate)
+
+		// Partners and the API keys they book with
+		adminV2.GET("/partners", handlers.GetPartners)
+		adminV2.GET("/partners/:id", handlers.GetPartner)
+		adminV2.POST("/partners", handlers.CreatePartner)
+		adminV2.PUT("/partners/:id", handlers.UpdatePartner)
+		adminV2.GET("/partners/:id/api-keys", handlers.GetPartnerAPIKeys)
+		adminV2.POST("/partners/:id/api-keys", handlers.CreatePartnerAPIKey)
+		adminV2.DELETE("/partners/:id/api-keys/:key_id", handlers.RevokePartnerAPIKey)
+	}
+
+	// Partner routes (v2 only), authenticated with an X-API-Key header instead of a token.
+	// Each route lists the scope the key needs.
+	partnerV2 := v2Public.Group("/partner", auth.RequireAPIKey(database.DB), limiter.Middleware(ratelimit.GROUP_PARTNER))
+	{
+		partnerV2.GET("/cars", auth.RequireScope(models.SCOPE_CARS_READ), handlers.GetCars)
+		partnerV2.GET("/cars/available", auth.RequireScope(models.SCOPE_CARS_READ), handlers.GetAvailableCars)
+		partnerV2.POST("/bookings/quote", auth.RequireScope(models.SCOPE_BOOKINGS_CREATE), handlers.QuotePartnerBooking)
+		partnerV2.POST("/bookings", auth.RequireScope(models.SCOPE_BOOKINGS_CREATE), idempotent, handlers.CreatePartnerBooking)
+		partnerV2.GET("/bookings", auth.RequireScope(models.SCOPE_BOOKINGS_READ), handlers.GetPartnerBookings)
+		partnerV2.GET("/bookings/:id", auth.RequireScope(models.SCOPE_BOOKINGS_READ), handlers.GetPartnerBooking)
 	}
 
 	// Health check route
@@ -0,0 +1,258 @@
+package scheduler
+
+import (
+	"car-rental/pkg/models"
+	"car-rental/pkg/utils"
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Default settings, overridable through the environment
+const (
+	DEFAULT_INTERVAL         = 5 * time.Minute
+	DEFAULT_RESERVATION_HOLD = 24 * time.Hour
+	MAX_KEPT_RUNS            = 50
+)
+
+// Reason recorded on reservations cancelled by the scheduler
+const RESERVATION_EXPIRED_REASON = "Reservation hold expired before confirmation"
+
+// Clock provides the current time, so tests can run the scheduler at any moment
+type Clock interface {
+	Now() time.Time
+}
+
+// SystemClock is the wall clock
+type SystemClock struct{}
+
+func (SystemClock) Now() time.Time {
+	return time.Now()
+}
+
+// Config controls how often the scheduler runs and how long reservations are held
+type Config struct {
+	Interval        time.Duration
+	ReservationHold time.Duration
+}
+
+// ConfigFromEnv reads SCHEDULER_INTERVAL and RESERVATION_HOLD (Go durations such as "5m" or "24h")
+func ConfigFromEnv() Config {
+	return Config{
+		Interval:        utils.GetEnvDuration("SCHEDULER_INTERVAL", DEFAULT_INTERVAL),
+		ReservationHold: utils.GetEnvDuration("RESERVATION_HOLD", DEFAULT_RESERVATION_HOLD),
+	}
+}
+
+// RunResult is what a single scheduler run changed
+type RunResult struct {
+	StartedAt           time.Time `json:"started_at"`
+	FinishedAt          time.Time `json:"finished_at"`
+	OverdueFlagged      []int     `json:"overdue_flagged"`
+	ReservationsExpired []int     `json:"reservations_expired"`
+	IdempotencyPurged   int64     `json:"idempotency_keys_purged"`
+	Errors              []string  `json:"errors"`
+}
+
+// Scheduler periodically flags overdue bookings and cancels expired reservations
+type Scheduler struct {
+	db     *gorm.DB
+	clock  Clock
+	config Config
+
+	mu   sync.Mutex
+	runs []RunResult
+}
+
+// Runner is the scheduler started by the application, read by the admin endpoints
+var Runner *Scheduler
+
+func New(db *gorm.DB, clock Clock, config Config) *Scheduler {
+	return &Scheduler{db: db, clock: clock, config: config}
+}
+
+// Start runs the jobs immediately and then on every interval until ctx is cancelled
+func (s *Scheduler) Start(ctx context.Context) {
+	log.Printf("Starting scheduler (interval %v, reservation hold %v)", s.config.Interval, s.config.ReservationHold)
+
+	ticker := time.NewTicker(s.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		s.RunOnce(ctx)
+
+		select {
+		case <-ctx.Done():
+			log.Println("Scheduler stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce executes every job a single time at the clock's current time and records the result
+func (s *Scheduler) RunOnce(ctx context.Context) RunResult {
+	now := s.clock.Now()
+	result := RunResult{
+		StartedAt:           now,
+		OverdueFlagged:      []int{},
+		ReservationsExpired: []int{},
+		Errors:              []string{},
+	}
+
+	db := s.db.WithContext(ctx)
+
+	flagged, err := flagOverdueBookings(db, now)
+	if err != nil {
+		result.Errors = append(result.Errors, "flag overdue bookings: "+err.Error())
+	}
+	result.OverdueFlagged = append(result.OverdueFlagged, flagged...)
+
+	expired, err := expireReservations(db, now, s.config.ReservationHold)
+	if err != nil {
+		result.Errors = append(result.Errors, "expire reservations: "+err.Error())
+	}
+	result.ReservationsExpired = append(result.ReservationsExpired, expired...)
+
+	purged, err := purgeIdempotencyKeys(db, now)
+	if err != nil {
+		result.Errors = append(result.Errors, "purge idempotency keys: "+err.Error())
+	}
+	result.IdempotencyPurged = purged
+
+	result.FinishedAt = s.clock.Now()
+	s.record(result)
+
+	if len(flagged) > 0 || len(expired) > 0 || len(result.Errors) > 0 {
+		log.Printf("Scheduler run: %d overdue flagged, %d reservations expired, %d errors",
+			len(flagged), len(expired), len(result.Errors))
+	}
+
+	return result
+}
+
+// Runs returns the most recent results, newest first
+func (s *Scheduler) Runs() []RunResult {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	runs := make([]RunResult, len(s.runs))
+	for i := range s.runs {
+		runs[i] = s.runs[len(s.runs)-1-i]
+	}
+	return runs
+}
+
+// Config returns the settings the scheduler runs with
+func (s *Scheduler) Config() Config {
+	return s.config
+}
+
+func (s *Scheduler) record(result RunResult) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.runs = append(s.runs, result)
+	if len(s.runs) > MAX_KEPT_RUNS {
+		s.runs = s.runs[len(s.runs)-MAX_KEPT_RUNS:]
+	}
+}
+
+// flagOverdueBookings marks picked up bookings whose rental period has ended as overdue.
+// Reservations that never left the branch are not late returns.
+func flagOverdueBookings(db *gorm.DB, now time.Time) ([]int, error) {
+	var candidates []models.Booking
+	err := db.Model(&models.Booking{}).
+		Where("status = ? AND overdue = ? AND end_rent < ?", models.BOOKING_STATUS_PICKED_UP, false, now).
+		Select("no", "start_rent", "end_rent").
+		Find(&candidates).Error
+	if err != nil {
+		return nil, err
+	}
+
+	due := []int{}
+	for _, booking := range candidates {
+		// The car is only late once every charged day has run out
+		if now.Before(utils.RentalDueAt(booking.StartRent, booking.EndRent)) {
+			continue
+		}
+		due = append(due, booking.No)
+	}
+
+	flagged := []int{}
+	if len(due) == 0 {
+		return flagged, nil
+	}
+
+	// The UPDATE checks the status again, so a booking returned since it was read is left alone
+	var updated []models.Booking
+	err = db.Model(&updated).
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "no"}}}).
+		Where("no IN ? AND status = ? AND overdue = ?", due, models.BOOKING_STATUS_PICKED_UP, false).
+		Updates(map[string]interface{}{"overdue": true, "overdue_flagged_at": now}).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, booking := range updated {
+		flagged = append(flagged, booking.No)
+	}
+	return flagged, nil
+}
+
+// purgeIdempotencyKeys deletes idempotency keys past their expiry, after which a retry
+// runs as a new request
+func purgeIdempotencyKeys(db *gorm.DB, now time.Time) (int64, error) {
+	result := db.Where("expires_at <= ?", now).Delete(&models.IdempotencyKey{})
+	return result.RowsAffected, result.Error
+}
+
+// expireReservations cancels pending reservations that were not confirmed within the hold.
+// The cancellation, the voucher release and the payment status refresh commit together, so
+// a failure leaves the reservations pending for the next run to retry.
+func expireReservations(db *gorm.DB, now time.Time, hold time.Duration) ([]int, error) {
+	expired := []int{}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// A single UPDATE ... RETURNING leaves alone any reservation confirmed in the meantime
+		var cancelled []models.Booking
+		err := tx.Model(&cancelled).
+			Clauses(clause.Returning{Columns: []clause.Column{{Name: "no"}}}).
+			Where("status = ? AND created_at < ?", models.BOOKING_STATUS_PENDING, now.Add(-hold)).
+			Updates(map[string]interface{}{
+				"status":              models.BOOKING_STATUS_CANCELLED,
+				"finished":            false,
+				"cancelled_at":        now,
+				"cancellation_reason": RESERVATION_EXPIRED_REASON,
+			}).Error
+		if err != nil {
+			return err
+		}
+
+		for _, booking := range cancelled {
+			expired = append(expired, booking.No)
+		}
+
+		if err := utils.ReleaseVoucherRedemptions(tx, expired, now); err != nil {
+			return err
+		}
+
+		// Nothing is owed on an expired reservation, so any deposit is now due back
+		for _, no := range expired {
+			var booking models.Booking
+			if err := tx.First(&booking, no).Error; err != nil {
+				return err
+			}
+			if _, err := utils.RefreshPaymentStatus(tx, &booking); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return []int{}, err
+	}
+	return expired, nil
+}
@@ -0,0 +1,139 @@
+package scheduler
+
+import (
+	"car-rental/pkg/models"
+	"car-rental/pkg/money"
+	"car-rental/pkg/testutil"
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+)
+
+// fakeClock always reports the same moment
+type fakeClock struct {
+	now time.Time
+}
+
+func (c fakeClock) Now() time.Time {
+	return c.now
+}
+
+func TestRunOnceExpiresReservationsPastTheHold(t *testing.T) {
+	db := testutil.DB(t)
+	f := testutil.NewFixture(t, db, "Scheduler test", 5)
+
+	now := time.Date(2030, 3, 10, 9, 0, 0, 0, time.UTC)
+	hold := 24 * time.Hour
+	start, end := now.AddDate(0, 0, 7), now.AddDate(0, 0, 9)
+
+	stale := f.Book(t, models.BOOKING_STATUS_PENDING, start, end, now.Add(-hold-time.Minute))
+	fresh := f.Book(t, models.BOOKING_STATUS_PENDING, start, end, now.Add(-hold+time.Minute))
+	confirmed := f.Book(t, models.BOOKING_STATUS_CONFIRMED, start, end, now.Add(-hold-time.Hour))
+
+	voucher := models.Voucher{
+		Code:         fmt.Sprintf("SCHED%d", time.Now().UnixNano()%1e9),
+		DiscountType: models.VOUCHER_TYPE_FIXED,
+		Amount:       money.Rupiah(10000),
+		ValidFrom:    now.AddDate(0, -1, 0),
+		ValidUntil:   now.AddDate(0, 1, 0),
+	}
+	if err := db.Create(&voucher).Error; err != nil {
+		t.Fatalf("create voucher: %v", err)
+	}
+	t.Cleanup(func() { db.Delete(&voucher) })
+
+	redemption := models.VoucherRedemption{
+		VoucherID:  voucher.No,
+		BookingID:  stale.No,
+		CustomerID: f.Customer.No,
+		Discount:   money.Rupiah(10000),
+		RedeemedAt: *stale.CreatedAt,
+	}
+	if err := db.Create(&redemption).Error; err != nil {
+		t.Fatalf("create voucher redemption: %v", err)
+	}
+
+	s := New(db, fakeClock{now: now}, Config{Interval: time.Minute, ReservationHold: hold})
+	result := s.RunOnce(context.Background())
+
+	if len(result.Errors) > 0 {
+		t.Fatalf("run errors: %v", result.Errors)
+	}
+	if !slices.Contains(result.ReservationsExpired, stale.No) {
+		t.Errorf("reservation %d past the hold was not expired: %v", stale.No, result.ReservationsExpired)
+	}
+	for _, kept := range []models.Booking{fresh, confirmed} {
+		if slices.Contains(result.ReservationsExpired, kept.No) {
+			t.Errorf("booking %d was expired but should be kept", kept.No)
+		}
+	}
+
+	expired := f.Reload(t, stale)
+	if expired.Status != models.BOOKING_STATUS_CANCELLED || expired.CancelledAt == nil || !expired.CancelledAt.Equal(now) {
+		t.Errorf("expired reservation has status %q cancelled at %v, want cancelled at %v", expired.Status, expired.CancelledAt, now)
+	}
+	if expired.CancellationReason != RESERVATION_EXPIRED_REASON {
+		t.Errorf("cancellation reason = %q, want %q", expired.CancellationReason, RESERVATION_EXPIRED_REASON)
+	}
+	if got := f.Reload(t, fresh).Status; got != models.BOOKING_STATUS_PENDING {
+		t.Errorf("reservation within the hold has status %q, want pending", got)
+	}
+
+	if err := db.First(&redemption, redemption.No).Error; err != nil {
+		t.Fatalf("reload voucher redemption: %v", err)
+	}
+	if redemption.ReleasedAt == nil || !redemption.ReleasedAt.Equal(now) {
+		t.Errorf("voucher redemption released at %v, want %v", redemption.ReleasedAt, now)
+	}
+
+	// A later run has nothing left to expire for these bookings
+	again := s.RunOnce(context.Background())
+	if slices.Contains(again.ReservationsExpired, stale.No) {
+		t.Errorf("reservation %d was expired twice", stale.No)
+	}
+}
+
+func TestRunOnceFlagsOnlyPickedUpBookingsPastTheirDueTime(t *testing.T) {
+	db := testutil.DB(t)
+	f := testutil.NewFixture(t, db, "Scheduler test", 5)
+
+	now := time.Date(2030, 3, 10, 9, 0, 0, 0, time.UTC)
+	created := now.AddDate(0, 0, -10)
+	start, end := now.AddDate(0, 0, -5), now.AddDate(0, 0, -2)
+
+	late := f.Book(t, models.BOOKING_STATUS_PICKED_UP, start, end, created)
+	neverPickedUp := f.Book(t, models.BOOKING_STATUS_CONFIRMED, start, end, created)
+	notDueYet := f.Book(t, models.BOOKING_STATUS_PICKED_UP, start, now.AddDate(0, 0, 2), created)
+
+	// Reservations are only expired once they are a year old, so pending bookings stay put
+	s := New(db, fakeClock{now: now}, Config{Interval: time.Minute, ReservationHold: 365 * 24 * time.Hour})
+	result := s.RunOnce(context.Background())
+
+	if len(result.Errors) > 0 {
+		t.Fatalf("run errors: %v", result.Errors)
+	}
+	if !slices.Contains(result.OverdueFlagged, late.No) {
+		t.Errorf("picked up booking %d past its due time was not flagged: %v", late.No, result.OverdueFlagged)
+	}
+	for _, kept := range []models.Booking{neverPickedUp, notDueYet} {
+		if slices.Contains(result.OverdueFlagged, kept.No) {
+			t.Errorf("booking %d was flagged overdue but should not be", kept.No)
+		}
+		if f.Reload(t, kept).Overdue {
+			t.Errorf("booking %d is stored as overdue", kept.No)
+		}
+	}
+
+	flagged := f.Reload(t, late)
+	if !flagged.Overdue || flagged.OverdueFlaggedAt == nil || !flagged.OverdueFlaggedAt.Equal(now) {
+		t.Errorf("late booking overdue = %v flagged at %v, want true at %v", flagged.Overdue, flagged.OverdueFlaggedAt, now)
+	}
+
+	// The clock moving on does not flag the same booking again
+	later := New(db, fakeClock{now: now.Add(time.Hour)}, s.Config()).RunOnce(context.Background())
+	if slices.Contains(later.OverdueFlagged, late.No) {
+		t.Errorf("booking %d was flagged twice", late.No)
+	}
+}
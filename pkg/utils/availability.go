@@ -0,0 +1,191 @@
+package utils
+
+import (
+	"car-rental/pkg/models"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CarAvailability describes how many units of a car model are free over a rental window
+type CarAvailability struct {
+	FleetSize int `json:"fleet_size"`
+	Reserved  int `json:"reserved"`
+	Available int `json:"available"`
+}
+
+// ActiveBookingsScope restricts a booking query to bookings that still hold a car
+func ActiveBookingsScope(db *gorm.DB) *gorm.DB {
+	return db.Where("status IN ?", models.ActiveBookingStatuses)
+}
+
+// OverlappingBookingsScope restricts a booking query to bookings of a car whose
+// [start_rent, end_rent] interval intersects the given window (bounds inclusive)
+func OverlappingBookingsScope(carID int, start, end time.Time) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("cars_id = ? AND start_rent <= ? AND end_rent >= ?", carID, end, start)
+	}
+}
+
+// PeakReservedUnits returns the highest number of units of a car that are held by
+// active bookings at any single moment within the window. Bookings that touch the
+// window but never overlap each other only count once, so two back-to-back rentals
+// share the same unit. excludeBookingID lets an update ignore the booking being edited.
+func PeakReservedUnits(db *gorm.DB, carID int, start, end time.Time, excludeBookingID int) (int, error) {
+	var bookings []models.Booking
+	query := db.Model(&models.Booking{}).
+		Scopes(ActiveBookingsScope, OverlappingBookingsScope(carID, start, end)).
+		Select("no", "start_rent", "end_rent")
+	if excludeBookingID > 0 {
+		query = query.Where("no <> ?", excludeBookingID)
+	}
+	if err := query.Find(&bookings).Error; err != nil {
+		return 0, err
+	}
+
+	return peakOverlap(bookings, start, end), nil
+}
+
+// PeakReservedUnitsByCar runs the same calculation as PeakReservedUnits for every car at once
+func PeakReservedUnitsByCar(db *gorm.DB, start, end time.Time) (map[int]int, error) {
+	var bookings []models.Booking
+	err := db.Model(&models.Booking{}).
+		Scopes(ActiveBookingsScope).
+		Where("start_rent <= ? AND end_rent >= ?", end, start).
+		Select("no", "cars_id", "start_rent", "end_rent").
+		Find(&bookings).Error
+	if err != nil {
+		return nil, err
+	}
+
+	byCar := make(map[int][]models.Booking)
+	for _, booking := range bookings {
+		byCar[booking.CarsID] = append(byCar[booking.CarsID], booking)
+	}
+
+	reserved := make(map[int]int, len(byCar))
+	for carID, carBookings := range byCar {
+		reserved[carID] = peakOverlap(carBookings, start, end)
+	}
+	return reserved, nil
+}
+
+// IsCarUnitPinned reports whether a unit is already pinned to another active booking
+// whose rental window overlaps the given one
+func IsCarUnitPinned(db *gorm.DB, unitID int, start, end time.Time, excludeBookingID int) (bool, error) {
+	var count int64
+	err := db.Model(&models.Booking{}).
+		Scopes(ActiveBookingsScope).
+		Where("car_unit_id = ? AND no <> ? AND start_rent <= ? AND end_rent >= ?", unitID, excludeBookingID, end, start).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// LockCar reloads a car that has not been removed and locks its row until the transaction
+// ends. Every write that reserves units of a car takes this lock before checking
+// availability, so two requests for the last unit run one after the other and the second
+// sees the first one's booking.
+func LockCar(tx *gorm.DB, carID int) (*models.Car, error) {
+	var car models.Car
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("deleted_at IS NULL").First(&car, carID).Error
+	return &car, err
+}
+
+// CheckCarAvailability works out how many units of the car are free for the whole window
+func CheckCarAvailability(db *gorm.DB, car *models.Car, start, end time.Time, excludeBookingID int) (*CarAvailability, error) {
+	reserved, err := PeakReservedUnits(db, car.No, start, end, excludeBookingID)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewCarAvailability(car, reserved), nil
+}
+
+// NewCarAvailability builds the availability of a car from the units already reserved
+func NewCarAvailability(car *models.Car, reserved int) *CarAvailability {
+	available := car.Stock - reserved
+	if available < 0 {
+		available = 0
+	}
+
+	return &CarAvailability{
+		FleetSize: car.Stock,
+		Reserved:  reserved,
+		Available: available,
+	}
+}
+
+type overlapEvent struct {
+	at    time.Time
+	delta int
+}
+
+// peakOverlap runs a sweep line over the bookings clipped to the window.
+// Intervals are closed, so at equal timestamps a start is counted before an end.
+func peakOverlap(bookings []models.Booking, start, end time.Time) int {
+	events := make([]overlapEvent, 0, len(bookings)*2)
+	for _, booking := range bookings {
+		from := booking.StartRent
+		if from.Before(start) {
+			from = start
+		}
+		to := booking.EndRent
+		if to.After(end) {
+			to = end
+		}
+		events = append(events, overlapEvent{at: from, delta: 1}, overlapEvent{at: to, delta: -1})
+	}
+
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].at.Equal(events[j].at) {
+			return events[i].delta > events[j].delta
+		}
+		return events[i].at.Before(events[j].at)
+	})
+
+	current, peak := 0, 0
+	for _, event := range events {
+		current += event.delta
+		if current > peak {
+			peak = current
+		}
+	}
+
+	return peak
+}
+
+// UnitsInUseAt counts, per car, the units held by active bookings at the given moment
+func UnitsInUseAt(db *gorm.DB, at time.Time) (map[int]int, error) {
+	var rows []struct {
+		CarsID int
+		InUse  int
+	}
+	err := db.Model(&models.Booking{}).
+		Scopes(ActiveBookingsScope).
+		Select("cars_id, COUNT(*) AS in_use").
+		Where("start_rent <= ? AND end_rent >= ?", at, at).
+		Group("cars_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	inUse := make(map[int]int, len(rows))
+	for _, row := range rows {
+		inUse[row.CarsID] = row.InUse
+	}
+	return inUse, nil
+}
+
+// SetCurrentAvailability fills the derived Available field of each car from the units in use
+func SetCurrentAvailability(cars []models.Car, inUse map[int]int) {
+	for i := range cars {
+		available := cars[i].Stock - inUse[cars[i].No]
+		if available < 0 {
+			available = 0
+		}
+		cars[i].Available = &available
+	}
+}
@@ -0,0 +1,79 @@
+package utils
+
+import (
+	"car-rental/pkg/models"
+	"testing"
+	"time"
+)
+
+func TestPeakOverlap(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2030, 3, d, 10, 0, 0, 0, time.UTC)
+	}
+	booking := func(from, to int) models.Booking {
+		return models.Booking{StartRent: day(from), EndRent: day(to)}
+	}
+
+	tests := []struct {
+		name     string
+		bookings []models.Booking
+		want     int
+	}{
+		{"no bookings", nil, 0},
+		{"one booking", []models.Booking{booking(2, 4)}, 1},
+		{"disjoint bookings share a unit", []models.Booking{booking(1, 2), booking(5, 6)}, 1},
+		{"overlapping bookings", []models.Booking{booking(1, 4), booking(3, 6)}, 2},
+		{"nested bookings", []models.Booking{booking(1, 9), booking(2, 3), booking(4, 5)}, 2},
+		{"three at once", []models.Booking{booking(1, 5), booking(2, 6), booking(3, 7), booking(8, 9)}, 3},
+		{"a return and a pickup at the same moment overlap", []models.Booking{booking(1, 3), booking(3, 5)}, 2},
+		{"bookings overlapping only outside the window", []models.Booking{booking(1, 3), booking(2, 5), booking(4, 9)}, 2},
+	}
+
+	for _, tt := range tests {
+		if got := peakOverlap(tt.bookings, day(3), day(8)); got != tt.want {
+			t.Errorf("%s: peak = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPeakOverlapClipsBookingsToTheWindow(t *testing.T) {
+	start := time.Date(2030, 3, 10, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 0, 3)
+
+	// Both bookings overlap each other only before the window opens
+	bookings := []models.Booking{
+		{StartRent: start.AddDate(0, 0, -5), EndRent: start.AddDate(0, 0, -1)},
+		{StartRent: start.AddDate(0, 0, -2), EndRent: start.AddDate(0, 0, 1)},
+	}
+	if got := peakOverlap(bookings, start, end); got != 1 {
+		t.Errorf("peak = %d, want 1", got)
+	}
+}
+
+func TestNewCarAvailability(t *testing.T) {
+	tests := []struct {
+		stock, reserved, want int
+	}{
+		{3, 0, 3},
+		{3, 2, 1},
+		{3, 3, 0},
+		{2, 5, 0},
+	}
+	for _, tt := range tests {
+		got := NewCarAvailability(&models.Car{Stock: tt.stock}, tt.reserved)
+		if got.FleetSize != tt.stock || got.Reserved != tt.reserved || got.Available != tt.want {
+			t.Errorf("stock %d with %d reserved = %+v, want %d available", tt.stock, tt.reserved, got, tt.want)
+		}
+	}
+}
+
+func TestSetCurrentAvailability(t *testing.T) {
+	cars := []models.Car{{No: 1, Stock: 4}, {No: 2, Stock: 1}, {No: 3, Stock: 2}}
+	SetCurrentAvailability(cars, map[int]int{1: 1, 2: 3})
+
+	for i, want := range []int{3, 0, 2} {
+		if cars[i].Available == nil || *cars[i].Available != want {
+			t.Errorf("car %d available = %v, want %d", cars[i].No, cars[i].Available, want)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package utils
+
+import (
+	"car-rental/pkg/models"
+	"car-rental/pkg/money"
+	"math"
+	"time"
+)
+
+// Default multiplier applied to the daily rent for every day a car comes back late
+const DEFAULT_LATE_FEE_MULTIPLIER = 1.5
+
+// BookingCostBreakdown lists every charge of a booking next to the amount due
+type BookingCostBreakdown struct {
+	TotalCost         money.Amount `json:"total_cost"`
+	Discount          money.Amount `json:"discount"`
+	VoucherDiscount   money.Amount `json:"voucher_discount"`
+	TotalDriverCost   money.Amount `json:"total_driver_cost"`
+	RentalTax         money.Amount `json:"rental_tax"`
+	DriverTax         money.Amount `json:"driver_tax"`
+	OverdueDays       int          `json:"overdue_days"`
+	OverdueHours      int          `json:"overdue_hours"`
+	LateFeeMultiplier money.Rate   `json:"late_fee_multiplier"`
+	OverdueFee        money.Amount `json:"overdue_fee"`
+	FeeTax            money.Amount `json:"fee_tax"`
+	TaxTotal          money.Amount `json:"tax_total"`
+	GrandTotal        money.Amount `json:"grand_total"`
+}
+
+// OverdueCharge is the fee for returning a car after its rental period
+type OverdueCharge struct {
+	DueAt      time.Time    `json:"due_at"`
+	ReturnedAt time.Time    `json:"returned_at"`
+	Hours      int          `json:"hours"`
+	Multiplier money.Rate   `json:"multiplier"`
+	Fee        money.Amount `json:"fee"`
+}
+
+// BookingTotal is what the customer owes for a booking: rent minus discounts plus driver cost,
+// late fees and taxes
+func BookingTotal(booking *models.Booking) money.Amount {
+	return BookingSubtotal(booking) + BookingTax(booking)
+}
+
+// BookingSubtotal is the booking total before tax
+func BookingSubtotal(booking *models.Booking) money.Amount {
+	return booking.TotalCost - booking.Discount - booking.VoucherDiscount + booking.TotalDriverCost + booking.OverdueFee
+}
+
+// BookingTax is the tax charged on a booking's rent, driver cost and late fees
+func BookingTax(booking *models.Booking) money.Amount {
+	return booking.RentalTax + booking.DriverTax + booking.FeeTax
+}
+
+// NewBookingCostBreakdown builds the cost breakdown from the amounts stored on a booking
+func NewBookingCostBreakdown(booking *models.Booking) BookingCostBreakdown {
+	return BookingCostBreakdown{
+		TotalCost:         booking.TotalCost,
+		Discount:          booking.Discount,
+		VoucherDiscount:   booking.VoucherDiscount,
+		TotalDriverCost:   booking.TotalDriverCost,
+		RentalTax:         booking.RentalTax,
+		DriverTax:         booking.DriverTax,
+		OverdueDays:       booking.OverdueHours / 24,
+		OverdueHours:      booking.OverdueHours % 24,
+		LateFeeMultiplier: booking.LateFeeMultiplier,
+		OverdueFee:        booking.OverdueFee,
+		FeeTax:            booking.FeeTax,
+		TaxTotal:          BookingTax(booking),
+		GrandTotal:        BookingTotal(booking),
+	}
+}
+
+// RentalDueAt is when the car has to be back. Every charged day covers 24 hours from
+// StartRent, so a rental charged for N days is due N×24h after it starts.
+func RentalDueAt(start, end time.Time) time.Time {
+	return start.Add(time.Duration(RentalDays(start, end)) * 24 * time.Hour)
+}
+
+// LateFeeMultiplier reads the configured late-fee multiplier (LATE_FEE_MULTIPLIER)
+func LateFeeMultiplier() money.Rate {
+	return money.RateFromFloat(GetEnvFloat("LATE_FEE_MULTIPLIER", DEFAULT_LATE_FEE_MULTIPLIER))
+}
+
+// CalculateOverdueCharge charges every started hour past the due time at dailyRent/24,
+// scaled by the late-fee multiplier. A full late day therefore costs dailyRent × multiplier.
+// The fee is rounded to the sen once, after the multiplier.
+func CalculateOverdueCharge(booking *models.Booking, dailyRent money.Amount, returnedAt time.Time, multiplier money.Rate) OverdueCharge {
+	charge := OverdueCharge{
+		DueAt:      RentalDueAt(booking.StartRent, booking.EndRent),
+		ReturnedAt: returnedAt,
+		Multiplier: multiplier,
+	}
+
+	late := returnedAt.Sub(charge.DueAt)
+	if late <= 0 {
+		return charge
+	}
+
+	charge.Hours = int(math.Ceil(late.Hours()))
+	charge.Fee = dailyRent.Fraction(int64(charge.Hours)*int64(multiplier), 24*money.RATE_SCALE)
+	return charge
+}
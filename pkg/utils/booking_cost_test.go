@@ -0,0 +1,43 @@
+package utils
+
+import (
+	"car-rental/pkg/models"
+	"car-rental/pkg/money"
+	"testing"
+	"time"
+)
+
+func TestCalculateOverdueCharge(t *testing.T) {
+	// Charged for three days, so the car is due back on the 4th at 10:00
+	start := time.Date(2030, 3, 1, 10, 0, 0, 0, time.UTC)
+	booking := &models.Booking{StartRent: start, EndRent: start.AddDate(0, 0, 2)}
+	due := start.AddDate(0, 0, 3)
+	dailyRent := money.Rupiah(100000)
+
+	tests := []struct {
+		name       string
+		returnedAt time.Time
+		multiplier money.Rate
+		wantHours  int
+		wantFee    money.Amount
+	}{
+		{"returned early", due.Add(-time.Hour), money.RateFromFloat(1.5), 0, 0},
+		{"returned on time", due, money.RateFromFloat(1.5), 0, 0},
+		{"a minute late is a started hour", due.Add(time.Minute), money.RateFromFloat(1.5), 1, money.Rupiah(6250)},
+		{"a full late day costs the daily rent times the multiplier", due.Add(24 * time.Hour), money.RateFromFloat(1.5), 24, money.Rupiah(150000)},
+		{"partial hours round up", due.Add(25*time.Hour + 30*time.Minute), money.RateFromFloat(1.5), 26, money.Rupiah(162500)},
+		{"one hour without a surcharge rounds to the sen", due.Add(time.Hour), money.RateFromFloat(1), 1, 416667},
+	}
+	for _, tt := range tests {
+		charge := CalculateOverdueCharge(booking, dailyRent, tt.returnedAt, tt.multiplier)
+		if !charge.DueAt.Equal(due) {
+			t.Errorf("%s: due at %v, want %v", tt.name, charge.DueAt, due)
+		}
+		if charge.Hours != tt.wantHours || charge.Fee != tt.wantFee {
+			t.Errorf("%s: %d hours costing %s, want %d hours costing %s", tt.name, charge.Hours, charge.Fee, tt.wantHours, tt.wantFee)
+		}
+		if charge.Multiplier != tt.multiplier {
+			t.Errorf("%s: multiplier %s, want %s", tt.name, charge.Multiplier, tt.multiplier)
+		}
+	}
+}
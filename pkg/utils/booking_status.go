@@ -0,0 +1,31 @@
+package utils
+
+import (
+	"car-rental/pkg/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RespondWithTransitionError reports a rejected status change using the same shape as
+// ReferentialIntegrityError, listing the transitions that are allowed from the current status
+func RespondWithTransitionError(c *gin.Context, entityType string, entityID int, from, to string) {
+	allowed := models.BookingTransitions[from]
+	if allowed == nil {
+		allowed = []string{}
+	}
+
+	errorResponse := ReferentialIntegrityError{
+		Message:    "Cannot move " + entityType + " from '" + from + "' to '" + to + "'.",
+		EntityType: entityType,
+		EntityID:   entityID,
+		Constraint: "invalid_status_transition",
+		Details: map[string]interface{}{
+			"current_status":      from,
+			"requested_status":    to,
+			"allowed_transitions": allowed,
+		},
+	}
+
+	c.JSON(http.StatusConflict, errorResponse)
+}
@@ -0,0 +1,45 @@
+package utils
+
+import (
+	"car-rental/pkg/models"
+	"car-rental/pkg/money"
+	"errors"
+	"math"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// CancellationCharge is the outcome of applying the cancellation policy to a booking
+type CancellationCharge struct {
+	HoursBeforeStart int                            `json:"hours_before_start"`
+	Tier             *models.CancellationPolicyTier `json:"tier"`
+	FeePercent       money.Rate                     `json:"fee_percent"`
+	Fee              money.Amount                   `json:"fee"`
+}
+
+// CalculateCancellationCharge picks the policy tier with the highest threshold that a
+// cancellation at the given moment still meets and applies its fee to the booking total
+// before tax; the fee is taxed on its own, like a late return fee. Cancelling after StartRent counts as zero hours before. Without a matching tier no fee is charged.
+func CalculateCancellationCharge(db *gorm.DB, booking *models.Booking, at time.Time) (*CancellationCharge, error) {
+	hoursBefore := int(math.Floor(booking.StartRent.Sub(at).Hours()))
+	if hoursBefore < 0 {
+		hoursBefore = 0
+	}
+
+	charge := &CancellationCharge{HoursBeforeStart: hoursBefore}
+
+	var tier models.CancellationPolicyTier
+	err := db.Where("min_hours_before_start <= ?", hoursBefore).Order("min_hours_before_start DESC").First(&tier).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return charge, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	charge.Tier = &tier
+	charge.FeePercent = tier.FeePercent
+	charge.Fee = BookingSubtotal(booking).Percent(tier.FeePercent)
+	return charge, nil
+}
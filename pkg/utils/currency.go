@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"car-rental/pkg/models"
+	"car-rental/pkg/money"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// FindExchangeRate returns the latest rate of a currency that is in effect at a time
+func FindExchangeRate(db *gorm.DB, currency string, at time.Time) (*models.ExchangeRate, error) {
+	var rate models.ExchangeRate
+	err := db.Where("currency = ? AND effective_from <= ?", currency, at).
+		Order("effective_from DESC, no DESC").
+		First(&rate).Error
+	if err != nil {
+		return nil, err
+	}
+	return &rate, nil
+}
+
+// BookingAmounts are the booking amounts shown by a currency conversion
+func BookingAmounts(booking *models.Booking) map[string]money.Amount {
+	return map[string]money.Amount{
+		"total_cost":           booking.TotalCost,
+		"discount":             booking.Discount,
+		"voucher_discount":     booking.VoucherDiscount,
+		"total_driver_cost":    booking.TotalDriverCost,
+		"overdue_fee":          booking.OverdueFee,
+		"tax_total":            BookingTax(booking),
+		"cancellation_fee":     booking.CancellationFee,
+		"cancellation_fee_tax": booking.CancellationFeeTax,
+		"grand_total":          BookingTotal(booking),
+		"amount_due":           BookingAmountDue(booking),
+	}
+}
+
+// ConvertBooking fills the converted amounts of a booking. A booking asked for in the
+// currency it was created with keeps its recorded rate, so its figures do not move with
+// later rates.
+func ConvertBooking(booking *models.Booking, rate *models.ExchangeRate) {
+	if recorded := booking.RecordedExchangeRate(); recorded != nil && recorded.Currency == rate.Currency {
+		rate = recorded
+	}
+	booking.Converted = rate.Convert(BookingAmounts(booking))
+}
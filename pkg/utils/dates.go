@@ -0,0 +1,28 @@
+package utils
+
+import (
+	"errors"
+	"time"
+)
+
+// Accepted layouts for date query parameters, tried in order
+var dateParamLayouts = []string{time.RFC3339, "2006-01-02"}
+
+// ParseDateParam parses a date query parameter given either as RFC 3339 or as YYYY-MM-DD (UTC)
+func ParseDateParam(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, errors.New("date is required")
+	}
+
+	for _, layout := range dateParamLayouts {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			return parsed, nil
+		}
+	}
+	return time.Time{}, errors.New("date must be RFC 3339 or YYYY-MM-DD")
+}
+
+// RentalDays counts the charged days of a rental, including both the start and the end date
+func RentalDays(start, end time.Time) int {
+	return int(end.Sub(start).Hours()/24) + 1
+}
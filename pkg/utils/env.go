@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GetEnv reads a string setting from the environment, falling back to the default when unset
+func GetEnv(key string, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// GetEnvList reads a comma-separated setting from the environment, dropping empty entries
+func GetEnvList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// GetEnvFloat reads a float setting from the environment, falling back to the default
+// when the variable is unset or malformed
+func GetEnvFloat(key string, fallback float64) float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		log.Printf("Invalid %s value %q, using default %v", key, value, fallback)
+		return fallback
+	}
+	return parsed
+}
+
+// GetEnvDuration reads a duration setting such as "5m" or "24h" from the environment,
+// falling back to the default when the variable is unset or malformed
+func GetEnvDuration(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	parsed, err := time.ParseDuration(value)
+	if err != nil || parsed <= 0 {
+		log.Printf("Invalid %s value %q, using default %v", key, value, fallback)
+		return fallback
+	}
+	return parsed
+}
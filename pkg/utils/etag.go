@@ -0,0 +1,89 @@
+package utils
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ETag writes a row version as an entity tag, e.g. "3"
+func ETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// SetETag sends the version of the row in a response
+func SetETag(c *gin.Context, version int) {
+	c.Header("ETag", ETag(version))
+}
+
+// RequireIfMatch rejects writes that don't say which version of the row they were made
+// against, so a client that never read the ETag can't overwrite newer changes blindly
+func RequireIfMatch() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if strings.TrimSpace(c.GetHeader("If-Match")) == "" {
+			c.AbortWithStatusJSON(http.StatusPreconditionRequired, gin.H{
+				"error": "If-Match header is required. Send the ETag of the copy you are updating.",
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// IfMatch reports whether a request may write a row at this version. Requests without an
+// If-Match header, or with "*", may write any version; v2 routes require the header with
+// RequireIfMatch, while legacy v1 routes accept requests without it.
+func IfMatch(c *gin.Context, version int) bool {
+	header := c.GetHeader("If-Match")
+	if header == "" {
+		return true
+	}
+
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || tag == ETag(version) {
+			return true
+		}
+	}
+	return false
+}
+
+// UpdateVersioned writes updates to a row only while it still has the version the request
+// was checked against. It reports false when another write changed the row first.
+func UpdateVersioned(db *gorm.DB, model interface{}, id, version int, updates interface{}) (bool, error) {
+	result := db.Model(model).Where("version = ?", version).Updates(updates)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected > 0 {
+		return true, nil
+	}
+
+	// An update without any fields writes nothing, which is only a conflict if the row moved on
+	var versions []int
+	if err := db.Model(model).Where("no = ?", id).Pluck("version", &versions).Error; err != nil {
+		return false, err
+	}
+	return len(versions) == 1 && versions[0] == version, nil
+}
+
+// RespondWithVersionConflict rejects a write made against an outdated copy of a row
+func RespondWithVersionConflict(c *gin.Context, entityType string, entityID, version int) {
+	errorResponse := ReferentialIntegrityError{
+		Message:    "The " + entityType + " was changed since it was read. Reload it and retry with its current ETag.",
+		EntityType: entityType,
+		EntityID:   entityID,
+		Constraint: "version_mismatch",
+		Details: map[string]interface{}{
+			"current_version": version,
+			"current_etag":    ETag(version),
+			"if_match":        c.GetHeader("If-Match"),
+		},
+	}
+
+	SetETag(c, version)
+	c.JSON(http.StatusPreconditionFailed, errorResponse)
+}
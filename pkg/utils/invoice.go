@@ -0,0 +1,113 @@
+package utils
+
+import (
+	"car-rental/pkg/models"
+	"car-rental/pkg/money"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// NextInvoiceNumber takes the next number of the year. The sequence row stays locked until
+// the transaction ends, so concurrent invoices wait for each other instead of sharing a number.
+func NextInvoiceNumber(tx *gorm.DB, at time.Time) (string, error) {
+	var next int
+	err := tx.Raw(`INSERT INTO invoice_sequences (year, last_number) VALUES (?, 1)
+		ON CONFLICT (year) DO UPDATE SET last_number = invoice_sequences.last_number + 1
+		RETURNING last_number`, at.Year()).Scan(&next).Error
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("INV-%d-%06d", at.Year(), next), nil
+}
+
+// InvoiceLines lists the charges and discounts stored on a booking, skipping empty ones
+func InvoiceLines(booking *models.Booking) []models.InvoiceLine {
+	days := RentalDays(booking.StartRent, booking.EndRent)
+	lines := []models.InvoiceLine{{Description: fmt.Sprintf("Car rent (%d days)", days), Amount: booking.TotalCost}}
+
+	if booking.Discount > 0 {
+		lines = append(lines, models.InvoiceLine{Description: "Membership discount", Amount: -booking.Discount})
+	}
+	if booking.VoucherDiscount > 0 {
+		description := "Voucher discount"
+		if booking.PromoCode != nil {
+			description = fmt.Sprintf("Voucher discount (%s)", *booking.PromoCode)
+		}
+		lines = append(lines, models.InvoiceLine{Description: description, Amount: -booking.VoucherDiscount})
+	}
+	if booking.TotalDriverCost > 0 {
+		lines = append(lines, models.InvoiceLine{Description: fmt.Sprintf("Driver cost (%d days)", days), Amount: booking.TotalDriverCost})
+	}
+	if booking.OverdueFee > 0 {
+		description := fmt.Sprintf("Late return fee (%d hours × %s)", booking.OverdueHours, booking.LateFeeMultiplier)
+		lines = append(lines, models.InvoiceLine{Description: description, Amount: booking.OverdueFee})
+	}
+
+	taxes := []struct {
+		description string
+		amount      money.Amount
+	}{
+		{"Tax on rental", booking.RentalTax},
+		{"Tax on driver cost", booking.DriverTax},
+		{"Tax on late return fee", booking.FeeTax},
+	}
+	for _, tax := range taxes {
+		if tax.amount > 0 {
+			lines = append(lines, models.InvoiceLine{Description: tax.description, Amount: tax.amount})
+		}
+	}
+
+	for i := range lines {
+		lines[i].Position = i + 1
+	}
+	return lines
+}
+
+// IssueInvoice numbers and stores the invoice of a returned booking
+func IssueInvoice(tx *gorm.DB, booking *models.Booking, at time.Time) (*models.Invoice, error) {
+	var customer models.Customer
+	if err := tx.First(&customer, booking.CustomerID).Error; err != nil {
+		return nil, err
+	}
+
+	var car models.Car
+	if err := tx.First(&car, booking.CarsID).Error; err != nil {
+		return nil, err
+	}
+
+	number, err := NextInvoiceNumber(tx, at)
+	if err != nil {
+		return nil, err
+	}
+
+	invoice := models.Invoice{
+		Number:       number,
+		BookingID:    booking.No,
+		IssuedAt:     at,
+		CustomerName: customer.Name,
+		CustomerNIK:  customer.NIK,
+		CarName:      car.Name,
+		StartRent:    booking.StartRent,
+		EndRent:      booking.EndRent,
+		ReturnedAt:   booking.ReturnedAt,
+		Lines:        InvoiceLines(booking),
+	}
+	invoice.Subtotal = booking.TotalCost + booking.TotalDriverCost + booking.OverdueFee
+	invoice.DiscountTotal = booking.Discount + booking.VoucherDiscount
+	invoice.TaxTotal = BookingTax(booking)
+	invoice.Total = invoice.Subtotal - invoice.DiscountTotal + invoice.TaxTotal
+
+	if rate := booking.RecordedExchangeRate(); rate != nil {
+		convertedTotal := invoice.Total.Exchange(rate.Rate)
+		invoice.Currency = &rate.Currency
+		invoice.ExchangeRate = &rate.Rate
+		invoice.ConvertedTotal = &convertedTotal
+	}
+
+	if err := tx.Create(&invoice).Error; err != nil {
+		return nil, err
+	}
+	return &invoice, nil
+}
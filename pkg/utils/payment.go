@@ -0,0 +1,82 @@
+package utils
+
+import (
+	"car-rental/pkg/models"
+	"car-rental/pkg/money"
+
+	"gorm.io/gorm"
+)
+
+// BookingBalance compares what a booking costs with what has been paid for it
+type BookingBalance struct {
+	AmountDue      money.Amount `json:"amount_due"`
+	AmountPaid     money.Amount `json:"amount_paid"`
+	AmountRefunded money.Amount `json:"amount_refunded"`
+	NetPaid        money.Amount `json:"net_paid"`
+	Outstanding    money.Amount `json:"outstanding"`
+	PaymentStatus  string       `json:"payment_status"`
+}
+
+// BookingAmountDue is what the customer owes: the cancellation fee and its tax for cancelled
+// bookings, otherwise the booking total
+func BookingAmountDue(booking *models.Booking) money.Amount {
+	if booking.Status == models.BOOKING_STATUS_CANCELLED {
+		return booking.CancellationFee + booking.CancellationFeeTax
+	}
+	return BookingTotal(booking)
+}
+
+// CalculateBookingBalance sums the payment ledger of a booking. A negative outstanding
+// amount means the customer paid too much and is due a refund.
+func CalculateBookingBalance(db *gorm.DB, booking *models.Booking) (*BookingBalance, error) {
+	var totals []struct {
+		Type  string
+		Total money.Amount
+	}
+	err := db.Model(&models.Payment{}).
+		Select("type, SUM(amount) AS total").
+		Where("booking_id = ?", booking.No).
+		Group("type").
+		Scan(&totals).Error
+	if err != nil {
+		return nil, err
+	}
+
+	balance := &BookingBalance{AmountDue: BookingAmountDue(booking)}
+	for _, total := range totals {
+		if total.Type == models.PAYMENT_TYPE_REFUND {
+			balance.AmountRefunded += total.Total
+		} else {
+			balance.AmountPaid += total.Total
+		}
+	}
+	balance.NetPaid = balance.AmountPaid - balance.AmountRefunded
+	balance.Outstanding = balance.AmountDue - balance.NetPaid
+
+	switch {
+	case balance.Outstanding < 0:
+		balance.PaymentStatus = models.PAYMENT_STATUS_REFUND_DUE
+	case balance.Outstanding == 0:
+		balance.PaymentStatus = models.PAYMENT_STATUS_PAID
+	case balance.NetPaid > 0:
+		balance.PaymentStatus = models.PAYMENT_STATUS_PARTIALLY_PAID
+	default:
+		balance.PaymentStatus = models.PAYMENT_STATUS_UNPAID
+	}
+
+	return balance, nil
+}
+
+// RefreshPaymentStatus recalculates the balance and stores the resulting payment status on the booking
+func RefreshPaymentStatus(db *gorm.DB, booking *models.Booking) (*BookingBalance, error) {
+	balance, err := CalculateBookingBalance(db, booking)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.Model(booking).Update("payment_status", balance.PaymentStatus).Error; err != nil {
+		return nil, err
+	}
+	booking.PaymentStatus = balance.PaymentStatus
+	return balance, nil
+}
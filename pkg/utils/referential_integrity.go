@@ -21,7 +21,7 @@ func CheckCustomerBookingConstraints(customerID int) *BookingConstraintInfo {
 	var totalBookings, activeBookings int64
 
 	database.DB.Model(&models.Booking{}).Where("customer_id = ?", customerID).Count(&totalBookings)
-	database.DB.Model(&models.Booking{}).Where("customer_id = ? AND finished = ?", customerID, false).Count(&activeBookings)
+	database.DB.Model(&models.Booking{}).Scopes(ActiveBookingsScope).Where("customer_id = ?", customerID).Count(&activeBookings)
 
 	return &BookingConstraintInfo{
 		TotalBookings:  totalBookings,
@@ -36,7 +36,7 @@ func CheckCarBookingConstraints(carID int) *BookingConstraintInfo {
 	var totalBookings, activeBookings int64
 
 	database.DB.Model(&models.Booking{}).Where("cars_id = ?", carID).Count(&totalBookings)
-	database.DB.Model(&models.Booking{}).Where("cars_id = ? AND finished = ?", carID, false).Count(&activeBookings)
+	database.DB.Model(&models.Booking{}).Scopes(ActiveBookingsScope).Where("cars_id = ?", carID).Count(&activeBookings)
 
 	return &BookingConstraintInfo{
 		TotalBookings:  totalBookings,
@@ -51,7 +51,22 @@ func CheckDriverBookingConstraints(driverID int) *BookingConstraintInfo {
 	var totalBookings, activeBookings int64
 
 	database.DB.Model(&models.Booking{}).Where("driver_id = ?", driverID).Count(&totalBookings)
-	database.DB.Model(&models.Booking{}).Where("driver_id = ? AND finished = ?", driverID, false).Count(&activeBookings)
+	database.DB.Model(&models.Booking{}).Scopes(ActiveBookingsScope).Where("driver_id = ?", driverID).Count(&activeBookings)
+
+	return &BookingConstraintInfo{
+		TotalBookings:  totalBookings,
+		ActiveBookings: activeBookings,
+		HasBookings:    totalBookings > 0,
+		HasActive:      activeBookings > 0,
+	}
+}
+
+// Check if a car unit is pinned to any bookings that would prevent deletion
+func CheckCarUnitBookingConstraints(unitID int) *BookingConstraintInfo {
+	var totalBookings, activeBookings int64
+
+	database.DB.Model(&models.Booking{}).Where("car_unit_id = ?", unitID).Count(&totalBookings)
+	database.DB.Model(&models.Booking{}).Scopes(ActiveBookingsScope).Where("car_unit_id = ?", unitID).Count(&activeBookings)
 
 	return &BookingConstraintInfo{
 		TotalBookings:  totalBookings,
@@ -76,8 +91,10 @@ func RespondWithConstraintError(c *gin.Context, entityType string, entityID int,
 	switch constraint {
 	case "active_bookings":
 		message = "Cannot delete " + entityType + " with active bookings. Please finish or cancel active bookings first."
-	case "finished_booking":
-		message = "Cannot delete finished booking. Finished bookings are kept for historical records."
+	case "not_pending":
+		message = "Cannot delete " + entityType + " past pending. It is kept for historical records, please cancel it instead."
+	case "has_payments":
+		message = "Cannot delete " + entityType + " with recorded payments. Please cancel it and refund the payments instead."
 	default:
 		message = "Cannot delete " + entityType + " due to referential integrity constraints."
 	}
@@ -99,6 +116,16 @@ func SoftDeleteCar(carID int) error {
 	return database.DB.Model(&models.Car{}).Where("no = ?", carID).Update("deleted_at", &now).Error
 }
 
+func SoftDeleteCarUnit(unitID int) error {
+	now := time.Now()
+	return database.DB.Model(&models.CarUnit{}).Where("no = ?", unitID).Update("deleted_at", &now).Error
+}
+
+func SoftDeleteVoucher(voucherID int) error {
+	now := time.Now()
+	return database.DB.Model(&models.Voucher{}).Where("no = ?", voucherID).Update("deleted_at", &now).Error
+}
+
 func SoftDeleteDriver(driverID int) error {
 	now := time.Now()
 	return database.DB.Model(&models.Driver{}).Where("no = ?", driverID).Update("deleted_at", &now).Error
@@ -0,0 +1,27 @@
+package utils
+
+import (
+	"car-rental/pkg/models"
+	"fmt"
+)
+
+// CheckRentalLength returns why a rental of the given number of days falls outside the car's
+// limits, or an empty string when it fits
+func CheckRentalLength(car *models.Car, days int) string {
+	if car.MinRentalDays != nil && days < *car.MinRentalDays {
+		return fmt.Sprintf("%s must be rented for at least %d days", car.Name, *car.MinRentalDays)
+	}
+	if car.MaxRentalDays != nil && days > *car.MaxRentalDays {
+		return fmt.Sprintf("%s can be rented for at most %d days", car.Name, *car.MaxRentalDays)
+	}
+	return ""
+}
+
+// CheckRentalLimits returns why a minimum and maximum rental length contradict each other,
+// or an empty string when they are consistent
+func CheckRentalLimits(minDays, maxDays *int) string {
+	if minDays != nil && maxDays != nil && *minDays > *maxDays {
+		return "Minimum rental days must not exceed maximum rental days"
+	}
+	return ""
+}
@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"car-rental/pkg/models"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// NormalizeVoucherCode makes codes case-insensitive by storing and matching them in upper case
+func NormalizeVoucherCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// FindVoucherByCode loads a voucher that has not been deleted, together with its car restrictions
+func FindVoucherByCode(db *gorm.DB, code string) (*models.Voucher, error) {
+	var voucher models.Voucher
+	err := db.Where("code = ? AND deleted_at IS NULL", NormalizeVoucherCode(code)).Preload("Cars").First(&voucher).Error
+	if err != nil {
+		return nil, err
+	}
+	return &voucher, nil
+}
+
+// CheckVoucher returns why a voucher cannot be used for a rental, or an empty string when it can
+func CheckVoucher(db *gorm.DB, voucher *models.Voucher, customerID, carID, days int, at time.Time) (string, error) {
+	if at.Before(voucher.ValidFrom) || at.After(voucher.ValidUntil) {
+		return fmt.Sprintf("Voucher %s is not valid at this time", voucher.Code), nil
+	}
+
+	if days < voucher.MinRentalDays {
+		return fmt.Sprintf("Voucher %s requires a rental of at least %d days", voucher.Code, voucher.MinRentalDays), nil
+	}
+
+	if !voucher.AppliesToCar(carID) {
+		return fmt.Sprintf("Voucher %s cannot be used for this car", voucher.Code), nil
+	}
+
+	return CheckVoucherUsage(db, voucher, customerID)
+}
+
+// CheckVoucherUsage returns why a voucher has no uses left, or an empty string when it has.
+// Released redemptions do not count.
+func CheckVoucherUsage(db *gorm.DB, voucher *models.Voucher, customerID int) (string, error) {
+	if voucher.UsageLimit != nil {
+		var used int64
+		err := db.Model(&models.VoucherRedemption{}).
+			Where("voucher_id = ? AND released_at IS NULL", voucher.No).
+			Count(&used).Error
+		if err != nil {
+			return "", err
+		}
+		if used >= int64(*voucher.UsageLimit) {
+			return fmt.Sprintf("Voucher %s has been fully redeemed", voucher.Code), nil
+		}
+	}
+
+	if voucher.PerCustomerLimit != nil {
+		var used int64
+		err := db.Model(&models.VoucherRedemption{}).
+			Where("voucher_id = ? AND customer_id = ? AND released_at IS NULL", voucher.No, customerID).
+			Count(&used).Error
+		if err != nil {
+			return "", err
+		}
+		if used >= int64(*voucher.PerCustomerLimit) {
+			return fmt.Sprintf("Voucher %s has already been used the maximum number of times by this customer", voucher.Code), nil
+		}
+	}
+
+	return "", nil
+}
+
+// ReleaseVoucherRedemptions gives the vouchers used on these bookings their uses back
+func ReleaseVoucherRedemptions(db *gorm.DB, bookingIDs []int, at time.Time) error {
+	if len(bookingIDs) == 0 {
+		return nil
+	}
+	return db.Model(&models.VoucherRedemption{}).
+		Where("booking_id IN ? AND released_at IS NULL", bookingIDs).
+		Update("released_at", at).Error
+}